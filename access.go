@@ -0,0 +1,421 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	adb "github.com/zach-klippenstein/goadb"
+)
+
+// shellAccess accesses device paths which the sync protocol cannot,
+// through shell commands run as a debuggable app with run-as,
+// or as the root user with su.
+type shellAccess struct {
+	pkg  string
+	root string
+	su   bool
+}
+
+var (
+	runAsRoots []*shellAccess
+	accessLock sync.Mutex
+
+	suAccess = &shellAccess{root: "/", su: true}
+)
+
+func (a *shellAccess) String() string {
+	if a.su {
+		return "root"
+	}
+
+	return "run-as " + a.pkg
+}
+
+// command returns cmd wrapped to run with the access' privileges.
+func (a *shellAccess) command(cmd string) string {
+	if a.su {
+		return "su -c " + shellQuote(cmd)
+	}
+
+	return "run-as " + shellQuote(a.pkg) + " sh -c " + shellQuote(cmd)
+}
+
+// getShellAccess returns the access for path, or nil if
+// it is accessed through the sync protocol. In root mode,
+// every path is accessed with su.
+func getShellAccess(path string, su bool) *shellAccess {
+	if su {
+		return suAccess
+	}
+
+	accessLock.Lock()
+	defer accessLock.Unlock()
+
+	path = filepath.Clean(path)
+
+	for _, a := range runAsRoots {
+		for _, root := range []string{a.root, filepath.Join("/data/data", a.pkg)} {
+			if path == root || strings.HasPrefix(path, root+"/") {
+				return a
+			}
+		}
+	}
+
+	return nil
+}
+
+// addRunAs checks that pkg can be accessed with run-as, and
+// registers the access to its data directory.
+func addRunAs(pkg string) (*shellAccess, error) {
+	device, err := getAdb()
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := runAdbShellCommand(device, "run-as "+shellQuote(pkg)+" pwd 2>&1")
+	if err != nil {
+		return nil, err
+	}
+
+	root := strings.TrimSpace(out)
+	if !strings.HasPrefix(root, "/") || strings.Contains(root, "\n") {
+		return nil, errors.New(root)
+	}
+
+	accessLock.Lock()
+	defer accessLock.Unlock()
+
+	for _, a := range runAsRoots {
+		if a.pkg == pkg {
+			return a, nil
+		}
+	}
+
+	a := &shellAccess{pkg: pkg, root: root}
+	runAsRoots = append(runAsRoots, a)
+
+	return a, nil
+}
+
+// checkSu checks that the device grants root access with su.
+func checkSu() error {
+	device, err := getAdb()
+	if err != nil {
+		return err
+	}
+
+	out, err := runAdbShellCommand(device, suAccess.command("id -u")+" 2>&1")
+	if err != nil {
+		return err
+	}
+
+	if out = strings.TrimSpace(out); out != "0" {
+		if out == "" {
+			out = "su is not available"
+		}
+
+		return errors.New(out)
+	}
+
+	return nil
+}
+
+// wrapAdbCommand returns cmd, which operates on path,
+// wrapped to run with the access path needs.
+func wrapAdbCommand(path, cmd string, su bool) string {
+	if a := getShellAccess(path, su); a != nil {
+		return a.command(cmd)
+	}
+
+	return cmd
+}
+
+// unixFileMode converts a raw unix file mode to an os.FileMode.
+func unixFileMode(m uint32) os.FileMode {
+	mode := os.FileMode(m & 0777)
+
+	switch m & 0170000 {
+	case 0040000:
+		mode |= os.ModeDir
+
+	case 0120000:
+		mode |= os.ModeSymlink
+
+	case 0140000:
+		mode |= os.ModeSocket
+
+	case 0010000:
+		mode |= os.ModeNamedPipe
+
+	case 0020000:
+		mode |= os.ModeDevice | os.ModeCharDevice
+
+	case 0060000:
+		mode |= os.ModeDevice
+	}
+
+	if m&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+
+	if m&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+
+	if m&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+
+	return mode
+}
+
+// parseStatLine parses a line of "stat -c '%f %s %Y %n'".
+func parseStatLine(line string) (*adb.DirEntry, error) {
+	fields := strings.SplitN(strings.TrimRight(line, "\r"), " ", 4)
+	if len(fields) < 4 {
+		return nil, errors.New(strings.TrimSpace(line))
+	}
+
+	mode, err := strconv.ParseUint(fields[0], 16, 32)
+	if err != nil {
+		return nil, errors.New(strings.TrimSpace(line))
+	}
+
+	size, _ := strconv.ParseInt(fields[1], 10, 64)
+	mtime, _ := strconv.ParseInt(fields[2], 10, 64)
+
+	return &adb.DirEntry{
+		Name:       filepath.Base(fields[3]),
+		Mode:       unixFileMode(uint32(mode)),
+		Size:       int32(size),
+		ModifiedAt: time.Unix(mtime, 0),
+	}, nil
+}
+
+func (a *shellAccess) stat(device *adb.Device, path string) (*adb.DirEntry, error) {
+	// Note: stat calls are deliberately not logged, like adbStat.
+	out, err := device.RunCommand(a.command("stat -c '%f %s %Y %n' " + shellQuote(path) + " 2>&1"))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseStatLine(strings.TrimSpace(out))
+}
+
+func (a *shellAccess) list(device *adb.Device, path string) ([]*adb.DirEntry, error) {
+	var entries []*adb.DirEntry
+
+	cmd := fmt.Sprintf("find %s -mindepth 1 -maxdepth 1 -exec stat -c '%%f %%s %%Y %%n' {} + 2>&1", shellQuote(findPath(path)))
+
+	out, err := device.RunCommand(a.command(cmd))
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries which cannot be read are skipped, unless
+	// the directory itself cannot be read.
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		entry, perr := parseStatLine(line)
+		if perr != nil {
+			err = perr
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 && err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+type shellStream struct {
+	io.ReadCloser
+	io.WriteCloser
+
+	cmd *exec.Cmd
+}
+
+func (s *shellStream) Close() error {
+	if s.WriteCloser != nil {
+		s.WriteCloser.Close()
+	}
+
+	if s.ReadCloser != nil {
+		s.ReadCloser.Close()
+	}
+
+	return s.cmd.Wait()
+}
+
+// openRead streams the file at path from the device with "adb exec-out".
+func (a *shellAccess) openRead(ctx context.Context, path string) (io.ReadCloser, error) {
+	cmd := exec.CommandContext(ctx, "adb", "exec-out", a.command("cat "+shellQuote(path)))
+
+	rd, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return &shellStream{ReadCloser: rd, cmd: cmd}, nil
+}
+
+// openWrite streams a file to path on the device with "adb exec-in".
+// Errors, if any, are returned on Close.
+func (a *shellAccess) openWrite(ctx context.Context, path string) (io.WriteCloser, error) {
+	cmd := exec.CommandContext(ctx, "adb", "exec-in", a.command("cat > "+shellQuote(path)))
+
+	wr, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return &shellStream{WriteCloser: wr, cmd: cmd}, nil
+}
+
+// adbOpenRead opens the file at path on the device for reading. In root
+// mode, the sync protocol is tried first, and the file is streamed
+// with su only if it lacks the permission to read it.
+func adbOpenRead(ctx context.Context, device *adb.Device, path string, su bool) (io.ReadCloser, error) {
+	a := getShellAccess(path, su)
+	if a == nil {
+		return device.OpenRead(path)
+	}
+
+	if a.su {
+		if rd, err := device.OpenRead(path); err == nil {
+			return rd, nil
+		}
+	}
+
+	if _, err := a.stat(device, path); err != nil {
+		return nil, err
+	}
+
+	return a.openRead(ctx, path)
+}
+
+// adbOpenWrite opens the file at path on the device for writing.
+func adbOpenWrite(device *adb.Device, path string, perm os.FileMode, su bool) (io.WriteCloser, error) {
+	if a := getShellAccess(path, su); a != nil {
+		return a.openWrite(context.Background(), path)
+	}
+
+	return device.OpenWrite(path, perm, time.Now())
+}
+
+// showRunAs asks for a package, to browse its data directory with run-as.
+func (p *dirPane) showRunAs() {
+	input := getStatusInput("Browse data of package (run-as):", false)
+
+	exit := func() {
+		statuspgs.SwitchToPage("statusmsg")
+		app.SetFocus(p.table)
+	}
+
+	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEnter:
+			pkg := strings.TrimSpace(input.GetText())
+			if pkg == "" {
+				break
+			}
+
+			go p.openRunAs(pkg)
+
+			fallthrough
+
+		case tcell.KeyEscape:
+			exit()
+			return nil
+		}
+
+		return event
+	})
+
+	statuspgs.AddAndSwitchToPage("runas", input, true)
+	app.SetFocus(input)
+}
+
+// openRunAs changes the pane to the data directory of pkg.
+func (p *dirPane) openRunAs(pkg string) {
+	if !checkAdb() {
+		return
+	}
+
+	a, err := addRunAs(pkg)
+	if err != nil {
+		showErrorMsg(fmt.Errorf("Cannot access %s: %s", pkg, err.Error()), false)
+		return
+	}
+
+	showInfoMsg("Changing directory to " + a.root + " (" + a.String() + ")")
+	p.jumpToEntry(mAdb, a.root, "")
+}
+
+// toggleRoot switches root mode for the pane. In root mode, the device
+// is accessed as the root user with su, and the pane title shows "root".
+func (p *dirPane) toggleRoot() {
+	if p.mode != mAdb {
+		showInfoMsg("Root mode is only available in ADB mode")
+		return
+	}
+
+	go func() {
+		su := !p.getSu()
+
+		if su {
+			if !checkAdb() {
+				return
+			}
+
+			if err := checkSu(); err != nil {
+				showErrorMsg(fmt.Errorf("Cannot enable root mode: %s", err.Error()), false)
+				return
+			}
+		}
+
+		if !p.getLock() {
+			return
+		}
+
+		p.su = su
+		p.setUnlock()
+
+		if su {
+			showInfoMsg("Root mode enabled")
+		} else {
+			showInfoMsg("Root mode disabled")
+		}
+
+		p.ChangeDir(false, false)
+	}()
+}
+
+func (p *dirPane) getSu() bool {
+	return p.su
+}
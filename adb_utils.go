@@ -11,7 +11,10 @@ import (
 	adb "github.com/zach-klippenstein/goadb"
 )
 
-var lastDeviceState adb.DeviceState
+var (
+	deviceSerial    string
+	lastDeviceState adb.DeviceState
+)
 
 func checkAdb() bool {
 	_, err := getAdb()
@@ -35,14 +38,42 @@ func getAdb() (*adb.Device, error) {
 	if state != lastDeviceState {
 		addLog("devices", fmt.Sprintf("%v", state), err != nil || state != adb.StateOnline)
 		lastDeviceState = state
+		deviceSerial = ""
 	}
 	if err != nil || state != adb.StateOnline {
 		return nil, fmt.Errorf("ADB device not found")
 	}
 
+	if deviceSerial == "" {
+		deviceSerial, _ = device.Serial()
+	}
+
 	return device, nil
 }
 
+func getDeviceSerial() string {
+	if _, err := getAdb(); err != nil {
+		return ""
+	}
+
+	return deviceSerial
+}
+
+// shellQuote quotes str for use as a single argument in a device shell command.
+func shellQuote(str string) string {
+	return "'" + strings.ReplaceAll(str, "'", `'\''`) + "'"
+}
+
+// findPath returns path with a trailing slash, for use as the start path
+// of find or du, which do not descend into symbolic links like /sdcard.
+func findPath(path string) string {
+	if strings.HasSuffix(path, "/") {
+		return path
+	}
+
+	return path + "/"
+}
+
 func runAdbShellCommand(device *adb.Device, cmd string) (string, error) {
 	logIndex := startLog(fmt.Sprintf("shell %s", cmd))
 	out, err := device.RunCommand(cmd)
@@ -57,28 +88,55 @@ func runAdbShellCommandContext(ctx context.Context, cmd string) (string, error)
 	return string(out), err
 }
 
-func adbStat(device *adb.Device, path string) (*adb.DirEntry, error) {
+func adbStat(device *adb.Device, path string, su bool) (*adb.DirEntry, error) {
 	// Note: stat calls are deliberately not logged to avoid clogging the log
 	// as they occur very frequently during normal navigation
+	if a := getShellAccess(path, su); a != nil {
+		return a.stat(device, path)
+	}
+
 	stat, err := device.Stat(path)
 	return stat, err
 }
 
-func adbListDirEntries(device *adb.Device, path string) (*adb.DirEntries, error) {
+func adbListDirEntries(device *adb.Device, path string, su bool) ([]*adb.DirEntry, error) {
 	logIndex := startLog(fmt.Sprintf("ls %s", path))
-	entries, err := device.ListDirEntries(path)
+	entries, err := adbReadDir(device, path, su)
 	updateLog(logIndex, "", err != nil)
 	return entries, err
 }
 
-func isAdbSymDir(testPath, name string) bool {
+// adbReadDir lists the entries of the directory at path, without "." and "..".
+func adbReadDir(device *adb.Device, path string, su bool) ([]*adb.DirEntry, error) {
+	var entries []*adb.DirEntry
+
+	if a := getShellAccess(path, su); a != nil {
+		return a.list(device, path)
+	}
+
+	list, err := device.ListDirEntries(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for list.Next() {
+		entry := list.Entry()
+		if entry.Name != "." && entry.Name != ".." {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, list.Err()
+}
+
+func isAdbSymDir(testPath, name string, su bool) bool {
 	device, err := getAdb()
 	if err != nil {
 		return false
 	}
 
 	cmd := fmt.Sprintf("ls -pd %s%s/", testPath, name)
-	out, err := runAdbShellCommand(device, cmd)
+	out, err := runAdbShellCommand(device, wrapAdbCommand(testPath, cmd, su))
 
 	if err != nil {
 		return false
@@ -132,14 +190,14 @@ func (o *operation) execAdbCmd(src, dst string, device *adb.Device) error {
 		param = srcfmt
 
 	default:
-		stat, err := adbStat(device, src)
+		stat, err := adbStat(device, src, o.su)
 		if err != nil {
 			return err
 		}
 
 		switch o.opmode {
 		case opRename:
-			_, err := adbStat(device, dst)
+			_, err := adbStat(device, dst, o.su)
 			if err == nil {
 				return fmt.Errorf("rename %s %s: file exists", src, dst)
 			}
@@ -167,7 +225,14 @@ func (o *operation) execAdbCmd(src, dst string, device *adb.Device) error {
 		}
 	}
 
-	cmd = cmd + param
+	cmd += param
+
+	if a := getShellAccess(src, o.su); a != nil {
+		cmd = a.command(cmd)
+	} else {
+		cmd = wrapAdbCommand(dst, cmd, o.su)
+	}
+
 	out, err := runAdbShellCommandContext(o.ctx, cmd)
 
 	if err != nil {
@@ -194,13 +259,13 @@ func (p *dirPane) adbListDir(testPath string, autocomplete bool) ([]string, bool
 		return nil, false
 	}
 
-	_, err = adbStat(device, testPath)
+	_, err = adbStat(device, testPath, p.getSu())
 	if err != nil {
 		showErrorMsg(err, autocomplete)
 		return nil, false
 	}
 
-	dent, err := adbListDirEntries(device, testPath)
+	dent, err := adbListDirEntries(device, testPath, p.getSu())
 	if err != nil {
 		showErrorMsg(err, autocomplete)
 		return nil, false
@@ -208,16 +273,12 @@ func (p *dirPane) adbListDir(testPath string, autocomplete bool) ([]string, bool
 
 	if !autocomplete {
 		p.pathList = nil
+		p.sizes = nil
 	}
 
-	for dent.Next() {
-		ent := dent.Entry()
+	for _, ent := range dent {
 		name := ent.Name
 
-		if name == ".." || name == "." {
-			continue
-		}
-
 		if p.getHidden() && strings.HasPrefix(name, ".") {
 			continue
 		}
@@ -232,9 +293,6 @@ func (p *dirPane) adbListDir(testPath string, autocomplete bool) ([]string, bool
 
 		p.pathList = append(p.pathList, ent)
 	}
-	if dent.Err() != nil {
-		return nil, false
-	}
 
 	return dlist, true
 }
@@ -0,0 +1,567 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/darkhz/tview"
+	"github.com/gdamore/tcell/v2"
+)
+
+type appEntry struct {
+	pkg         string
+	path        string
+	system      bool
+	versionName string
+	versionCode string
+}
+
+type appFilter int
+
+const (
+	appsUser appFilter = iota
+	appsSystem
+	appsAll
+)
+
+func (f appFilter) String() string {
+	return [...]string{"user", "system", "all"}[f]
+}
+
+type appsView struct {
+	pane    *dirPane
+	auxPane *dirPane
+
+	apps   []appEntry
+	filter appFilter
+	text   string
+	lock   sync.Mutex
+
+	table *tview.Table
+	title *tview.TextView
+}
+
+// parsePackageList parses the output of "pm list packages -f",
+// whose lines are of the form "package:<apk path>=<package>".
+func parsePackageList(out string, system bool) []appEntry {
+	var apps []appEntry
+
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "package:") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "package:")
+
+		i := strings.LastIndex(line, "=")
+		if i < 0 {
+			continue
+		}
+
+		apps = append(apps, appEntry{
+			pkg:    line[i+1:],
+			path:   line[:i],
+			system: system,
+		})
+	}
+
+	return apps
+}
+
+// parsePackageVersions parses the versions of all packages
+// from the output of "dumpsys package packages".
+func parsePackageVersions(out string) map[string][2]string {
+	var pkg string
+
+	versions := make(map[string][2]string)
+
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+
+		// The packages listed after this header are the system
+		// versions replaced by updates, not the installed ones.
+		if line == "Hidden system packages:" {
+			break
+		}
+
+		if strings.HasPrefix(line, "Package [") {
+			pkg, _, _ = strings.Cut(strings.TrimPrefix(line, "Package ["), "]")
+			if _, ok := versions[pkg]; ok {
+				pkg = ""
+			}
+
+			continue
+		}
+
+		if pkg == "" {
+			continue
+		}
+
+		v := versions[pkg]
+
+		if strings.HasPrefix(line, "versionName=") {
+			v[0] = strings.TrimPrefix(line, "versionName=")
+		} else {
+			for _, field := range strings.Fields(line) {
+				if strings.HasPrefix(field, "versionCode=") {
+					v[1] = strings.TrimPrefix(field, "versionCode=")
+				}
+			}
+		}
+
+		versions[pkg] = v
+	}
+
+	return versions
+}
+
+func getInstalledApps() ([]appEntry, error) {
+	device, err := getAdb()
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := runAdbShellCommand(device, "pm list packages -f -3")
+	if err != nil {
+		return nil, err
+	}
+
+	system, err := runAdbShellCommand(device, "pm list packages -f -s")
+	if err != nil {
+		return nil, err
+	}
+
+	apps := append(parsePackageList(user, false), parsePackageList(system, true)...)
+
+	// Note: the package dump is large, and is deliberately not logged.
+	if out, err := device.RunCommand("dumpsys package packages"); err == nil {
+		versions := parsePackageVersions(out)
+
+		for i := range apps {
+			apps[i].versionName = versions[apps[i].pkg][0]
+			apps[i].versionCode = versions[apps[i].pkg][1]
+		}
+	}
+
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].pkg < apps[j].pkg
+	})
+
+	return apps, nil
+}
+
+// getAppApks returns the paths of the base and split APKs of pkg.
+func getAppApks(pkg string) ([]string, error) {
+	var paths []string
+
+	device, err := getAdb()
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := runAdbShellCommand(device, "pm path "+shellQuote(pkg))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		if path, ok := strings.CutPrefix(strings.TrimSpace(line), "package:"); ok {
+			paths = append(paths, path)
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("No APKs found for %s", pkg)
+	}
+
+	return paths, nil
+}
+
+// runPackageCommand runs a package manager or activity manager command.
+// The package manager reports "Success" first, as with install, and the
+// activity manager prints nothing unless the command fails.
+func runPackageCommand(cmd string) error {
+	device, err := getAdb()
+	if err != nil {
+		return err
+	}
+
+	out, err := runAdbShellCommand(device, cmd+" 2>&1")
+	if err != nil {
+		return err
+	}
+
+	out = strings.TrimSpace(out)
+
+	switch {
+	case strings.HasPrefix(out, "Success"):
+		return nil
+
+	case out == "":
+		if strings.HasPrefix(cmd, "am ") {
+			return nil
+		}
+
+		return errors.New("no result from the package manager")
+	}
+
+	lines := strings.Split(out, "\n")
+
+	return errors.New(strings.TrimSpace(lines[0]))
+}
+
+// appsLocation is the virtual ADB location of the apps view,
+// listed with the volumes and accepted by change-dir.
+const appsLocation = "apps:"
+
+func (p *dirPane) showApps(auxPane *dirPane) {
+	if !checkAdb() {
+		return
+	}
+
+	av := &appsView{
+		pane:    p,
+		auxPane: auxPane,
+		table:   tview.NewTable(),
+		title:   newTextView(),
+	}
+
+	av.setupView()
+	av.load()
+}
+
+func (av *appsView) load() {
+	av.setTitle("loading")
+
+	go func() {
+		apps, err := getInstalledApps()
+
+		go app.QueueUpdateDraw(func() {
+			if err != nil {
+				av.setTitle(err.Error())
+				return
+			}
+
+			av.lock.Lock()
+			av.apps = apps
+			av.lock.Unlock()
+
+			av.render("")
+		})
+	}()
+}
+
+func (av *appsView) setTitle(status string) {
+	title := fmt.Sprintf("Apps (%s", av.filter.String())
+	if av.text != "" {
+		title += ", matching '" + av.text + "'"
+	}
+
+	if status != "" {
+		title += ", " + status
+	}
+
+	av.title.SetText(getStyle("title").tag() + tview.Escape(title+")"))
+}
+
+func (av *appsView) render(cursor string) {
+	var row, sel int
+
+	if entry, ok := av.selected(); ok && cursor == "" {
+		cursor = entry.pkg
+	}
+
+	av.lock.Lock()
+	defer av.lock.Unlock()
+
+	av.table.Clear()
+
+	for _, entry := range av.apps {
+		switch {
+		case av.filter == appsUser && entry.system, av.filter == appsSystem && !entry.system:
+			continue
+
+		case av.text != "" && !strings.Contains(strings.ToLower(entry.pkg), strings.ToLower(av.text)):
+			continue
+		}
+
+		kind := "user"
+		if entry.system {
+			kind = "system"
+		}
+
+		version := entry.versionName
+		if entry.versionCode != "" {
+			version += " (" + entry.versionCode + ")"
+		}
+
+		cells := []*tview.TableCell{
+			tview.NewTableCell(tview.Escape(entry.pkg) + " "),
+			tview.NewTableCell(tview.Escape(version) + " "),
+			tview.NewTableCell(kind + " "),
+			tview.NewTableCell(tview.Escape(filepath.Dir(entry.path))).SetExpansion(1),
+		}
+
+		for col, cell := range cells {
+			if col > 0 {
+				cell.SetStyle(getStyle("column").style())
+			}
+
+			av.table.SetCell(row, col, cell.SetReference(entry))
+		}
+
+		if entry.pkg == cursor {
+			sel = row
+		}
+
+		row++
+	}
+
+	av.setTitle(fmt.Sprintf("%d packages", row))
+	av.table.Select(sel, 0)
+}
+
+func (av *appsView) selected() (appEntry, bool) {
+	row, _ := av.table.GetSelection()
+
+	entry, ok := av.table.GetCell(row, 0).GetReference().(appEntry)
+
+	return entry, ok
+}
+
+// copyApks copies the APKs of entry to the directory of a local pane.
+// An app with splits is copied to a directory named after it.
+func (av *appsView) copyApks(entry appEntry) {
+	var dst *dirPane
+
+	for _, pane := range []*dirPane{av.auxPane, av.pane} {
+		if pane.mode == mLocal {
+			dst = pane
+			break
+		}
+	}
+
+	if dst == nil {
+		showErrorMsg(errors.New("No local pane to copy the APKs to"), false)
+		return
+	}
+
+	dpath := dst.getPath()
+
+	showInfoMsg(fmt.Sprintf("Copying APKs of %s, check operations view", entry.pkg))
+
+	go func() {
+		apks, err := getAppApks(entry.pkg)
+		if err != nil {
+			showErrorMsg(err, false)
+			return
+		}
+
+		if len(apks) == 1 {
+			_, err = startOperation(
+				dst,
+				&dirPane{path: filepath.Join(dpath, entry.pkg+".apk"), mode: mLocal},
+				opCopy,
+				false,
+				[]selection{{apks[0], mAdb}},
+			)
+		} else {
+			dir := filepath.Join(dpath, entry.pkg)
+			if entry.versionName != "" {
+				dir += "-" + entry.versionName
+			}
+
+			err = os.MkdirAll(dir, 0755)
+
+			for _, apk := range apks {
+				if err != nil {
+					break
+				}
+
+				_, err = startOperation(
+					dst,
+					&dirPane{path: filepath.Join(dir, filepath.Base(apk)), mode: mLocal},
+					opCopy,
+					true,
+					[]selection{{apk, mAdb}},
+				)
+			}
+
+			dst.ChangeDir(false, false)
+		}
+
+		if err != nil {
+			showErrorMsg(fmt.Errorf("Unable to copy APKs of %s: %s", entry.pkg, err.Error()), false)
+			return
+		}
+
+		showInfoMsg(fmt.Sprintf("Copied %d APK(s) of %s to %s", len(apks), entry.pkg, dpath))
+	}()
+}
+
+// runAction runs a package command on app, after confirming
+// it if confirm is set, and reloads the list if reload is set.
+func (av *appsView) runAction(entry appEntry, cmd, confirm, done string, reload bool) {
+	run := func() {
+		showInfoMsg(fmt.Sprintf("Running %s", cmd))
+
+		go func() {
+			if err := runPackageCommand(cmd); err != nil {
+				showErrorMsg(fmt.Errorf("%s: %s", entry.pkg, err.Error()), false)
+				return
+			}
+
+			showInfoMsg(done)
+
+			if reload {
+				go app.QueueUpdateDraw(av.load)
+			}
+		}()
+	}
+
+	if confirm == "" {
+		run()
+		return
+	}
+
+	input := getStatusInput(confirm+" (y/N)?", true)
+
+	exit := func() {
+		statuspgs.SwitchToPage("statusmsg")
+		app.SetFocus(av.table)
+	}
+
+	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEnter:
+			exit()
+
+			if strings.ToLower(input.GetText()) == "y" {
+				run()
+			}
+
+			return nil
+
+		case tcell.KeyEscape:
+			exit()
+			return nil
+		}
+
+		return event
+	})
+
+	statuspgs.AddAndSwitchToPage("confirm", input, true)
+	app.SetFocus(input)
+}
+
+func (av *appsView) filterInput() {
+	input := getStatusInput("Filter packages:", false)
+	input.SetText(av.text)
+
+	input.SetChangedFunc(func(text string) {
+		av.text = text
+		av.render("")
+	})
+
+	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEnter, tcell.KeyEscape:
+			statuspgs.SwitchToPage("statusmsg")
+			app.SetFocus(av.table)
+
+			return nil
+		}
+
+		return event
+	})
+
+	statuspgs.AddAndSwitchToPage("appsfilter", input, true)
+	app.SetFocus(input)
+}
+
+func (av *appsView) setupView() {
+	flex := tview.NewFlex().
+		AddItem(av.title, 1, 0, false).
+		AddItem(av.table, 0, 1, true).
+		AddItem(statuspgs, 1, 0, false).
+		SetDirection(tview.FlexRow)
+
+	exit := func() {
+		pages.SwitchToPage("main")
+		pages.RemovePage("apps")
+
+		app.SetFocus(av.pane.table)
+	}
+
+	av.table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		action := getKeyAction(kApps, event)
+
+		switch action {
+		case "apps-exit":
+			exit()
+			return nil
+
+		case "apps-refresh":
+			av.load()
+			return nil
+
+		case "apps-type":
+			av.filter = (av.filter + 1) % 3
+			av.render("")
+
+			return nil
+
+		case "apps-filter":
+			av.filterInput()
+			return nil
+		}
+
+		sel, ok := av.selected()
+		if !ok {
+			return event
+		}
+
+		pkg := shellQuote(sel.pkg)
+
+		switch action {
+		case "apps-data":
+			exit()
+			go av.pane.openRunAs(sel.pkg)
+
+		case "apps-copy":
+			av.copyApks(sel)
+
+		case "apps-uninstall":
+			cmd := "pm uninstall " + pkg
+			if sel.system {
+				cmd = "pm uninstall --user 0 " + pkg
+			}
+
+			av.runAction(sel, cmd, "Uninstall "+sel.pkg, "Uninstalled "+sel.pkg, true)
+
+		case "apps-clear":
+			av.runAction(sel, "pm clear "+pkg, "Clear all data of "+sel.pkg, "Cleared data of "+sel.pkg, false)
+
+		case "apps-stop":
+			av.runAction(sel, "am force-stop "+pkg, "", "Stopped "+sel.pkg, false)
+
+		default:
+			return event
+		}
+
+		return nil
+	})
+
+	av.table.SetSelectable(true, false)
+	av.table.SetBackgroundColor(tcell.ColorDefault)
+	av.table.SetSelectedStyle(getStyle("cursor").style())
+
+	pages.AddAndSwitchToPage("apps", flex, true)
+	app.SetFocus(av.table)
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/darkhz/tview"
+	"github.com/gdamore/tcell/v2"
+)
+
+type bookmark struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Mode   string `json:"mode"`
+	Serial string `json:"serial,omitempty"`
+}
+
+var (
+	bookmarks    []bookmark
+	bookmarkLock sync.Mutex
+)
+
+func loadBookmarks() error {
+	bookmarkLock.Lock()
+	defer bookmarkLock.Unlock()
+
+	bpath := configPath("bookmarks.json")
+	if bpath == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(bpath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	if err = json.Unmarshal(data, &bookmarks); err != nil {
+		return fmt.Errorf("%s: %s", bpath, err.Error())
+	}
+
+	return nil
+}
+
+func saveBookmarks() error {
+	bpath := configPath("bookmarks.json")
+	if bpath == "" {
+		return fmt.Errorf("No configuration directory")
+	}
+
+	data, err := json.MarshalIndent(bookmarks, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeConfigFile(bpath, data)
+}
+
+func getBookmarks() []bookmark {
+	bookmarkLock.Lock()
+	defer bookmarkLock.Unlock()
+
+	return append([]bookmark{}, bookmarks...)
+}
+
+func addBookmark(b bookmark) error {
+	bookmarkLock.Lock()
+	defer bookmarkLock.Unlock()
+
+	for i, bm := range bookmarks {
+		if bm.Name == b.Name {
+			bookmarks[i] = b
+			return saveBookmarks()
+		}
+	}
+
+	bookmarks = append(bookmarks, b)
+
+	return saveBookmarks()
+}
+
+func delBookmark(name string) error {
+	bookmarkLock.Lock()
+	defer bookmarkLock.Unlock()
+
+	for i, bm := range bookmarks {
+		if bm.Name == name {
+			bookmarks = append(bookmarks[:i], bookmarks[i+1:]...)
+			break
+		}
+	}
+
+	return saveBookmarks()
+}
+
+func (b bookmark) String() string {
+	location := b.Mode
+	if b.Serial != "" {
+		location += " " + b.Serial
+	}
+
+	return b.Name + " (" + location + "): " + b.Path
+}
+
+func (p *dirPane) gotoBookmark(b bookmark) {
+	mode, err := parseIfaceMode(b.Mode)
+	if err != nil {
+		showErrorMsg(err, false)
+		return
+	}
+
+	if mode == mAdb {
+		if !checkAdb() {
+			return
+		}
+
+		if b.Serial != "" && getDeviceSerial() != b.Serial {
+			showErrorMsg(fmt.Errorf("Device %s is not connected", b.Serial), false)
+			return
+		}
+	}
+
+	if !p.getLock() {
+		return
+	}
+
+	p.saveViewState()
+	p.setMode(mode)
+	p.setUnlock()
+
+	showInfoMsg("Changing directory to " + b.Path)
+	p.ChangeDir(false, false, b.Path)
+}
+
+func (p *dirPane) jumpToBookmark(index int) {
+	marks := getBookmarks()
+
+	if index < 0 || index >= len(marks) {
+		showInfoMsg(fmt.Sprintf("No bookmark #%d", index+1))
+		return
+	}
+
+	p.gotoBookmark(marks[index])
+}
+
+func (p *dirPane) showAddBookmarkInput() {
+	var serial string
+
+	path := filepath.Clean(p.getPath())
+
+	if p.mode == mAdb {
+		serial = getDeviceSerial()
+	}
+
+	input := getStatusInput("Bookmark name:", false)
+	input.SetText(filepath.Base(path))
+
+	exit := func() {
+		statuspgs.SwitchToPage("statusmsg")
+		app.SetFocus(p.table)
+	}
+
+	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEnter:
+			name := strings.TrimSpace(input.GetText())
+			if name == "" {
+				break
+			}
+
+			err := addBookmark(bookmark{
+				Name:   name,
+				Path:   path,
+				Mode:   p.mode.String(),
+				Serial: serial,
+			})
+			if err != nil {
+				showErrorMsg(err, false)
+			} else {
+				showInfoMsg("Bookmarked " + path + " as '" + name + "'")
+			}
+
+			fallthrough
+
+		case tcell.KeyEscape:
+			exit()
+			return nil
+		}
+
+		return event
+	})
+
+	statuspgs.AddAndSwitchToPage("bookmarkinput", input, true)
+	app.SetFocus(input)
+}
+
+func (p *dirPane) showBookmarks() {
+	if len(getBookmarks()) == 0 {
+		showInfoMsg("No bookmarks. Use " + getKeyNames("bookmark-add") + " to add one.")
+		return
+	}
+
+	input := getStatusInput("Bookmarks:", false)
+
+	bmtable := tview.NewTable()
+
+	flex := tview.NewFlex().
+		AddItem(bmtable, 0, 10, false).
+		SetDirection(tview.FlexRow)
+
+	exit := func() {
+		popupStatus(false)
+		pages.SwitchToPage("main")
+		statuspgs.SwitchToPage("statusmsg")
+		app.SetFocus(p.table)
+	}
+
+	reload := func(text string) {
+		var row int
+
+		bmtable.Clear()
+
+		for i, b := range getBookmarks() {
+			if !strings.Contains(strings.ToLower(b.String()), strings.ToLower(text)) {
+				continue
+			}
+
+			label := fmt.Sprintf("[::b]%d. %s", i+1, tview.Escape(b.String()))
+
+			cell := tview.NewTableCell(label)
+			cell.SetReference(b)
+			bmtable.SetCell(row, 0, cell.SetTextColor(tcell.ColorDefault))
+
+			row++
+		}
+
+		if row == 0 {
+			pages.HidePage("bookmarkmodal")
+		} else {
+			if pg, _ := pages.GetFrontPage(); pg != "bookmarkmodal" {
+				pages.SwitchToPage("bookmarkmodal").ShowPage("main")
+			}
+
+			resizemodal()
+		}
+
+		app.SetFocus(input)
+
+		bmtable.Select(0, 0)
+		bmtable.ScrollToBeginning()
+	}
+
+	highlighted := func() (bookmark, bool) {
+		row, _ := bmtable.GetSelection()
+
+		cell := bmtable.GetCell(row, 0)
+		if cell == nil {
+			return bookmark{}, false
+		}
+
+		ref := cell.GetReference()
+		if ref == nil {
+			return bookmark{}, false
+		}
+
+		return ref.(bookmark), true
+	}
+
+	input.SetChangedFunc(func(text string) {
+		reload(text)
+	})
+
+	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch getKeyAction(kBookmarks, event) {
+		case "bookmark-select":
+			b, ok := highlighted()
+			exit()
+
+			if ok {
+				p.gotoBookmark(b)
+			}
+
+			return nil
+
+		case "bookmark-delete":
+			b, ok := highlighted()
+			if !ok {
+				return nil
+			}
+
+			if err := delBookmark(b.Name); err != nil {
+				showErrorMsg(err, false)
+			}
+
+			if len(getBookmarks()) == 0 {
+				exit()
+				return nil
+			}
+
+			reload(input.GetText())
+			return nil
+
+		case "bookmark-exit":
+			exit()
+			return nil
+		}
+
+		switch event.Key() {
+		case tcell.KeyDown, tcell.KeyUp, tcell.KeyPgDn, tcell.KeyPgUp:
+			bmtable.InputHandler()(event, nil)
+			return nil
+		}
+
+		return event
+	})
+
+	bmtable.SetSelectedStyle(tcell.Style{}.
+		Bold(true).
+		Underline(true).
+		Reverse(true))
+
+	bmtable.SetSelectable(true, false)
+	bmtable.SetBackgroundColor(tcell.ColorDefault)
+
+	pages.AddAndSwitchToPage("bookmarkmodal", statusmodal(flex, bmtable), true).ShowPage("main")
+
+	statuspgs.AddAndSwitchToPage("bookmarks", input, true)
+	reload("")
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type appConfig struct {
+	Theme          string              `json:"theme"`
+	LSColors       *bool               `json:"lscolors"`
+	RestoreSession bool                `json:"restore_session"`
+	FuzzyDepth     int                 `json:"fuzzy_depth"`
+	Preview        bool                `json:"preview"`
+	PreviewSize    int                 `json:"preview_size"`
+	Openers        []fileOpener        `json:"openers"`
+	StagingDir     string              `json:"staging_dir"`
+	MediaScan      bool                `json:"media_scan"`
+	Keys           map[string][]string `json:"keys"`
+}
+
+var config appConfig
+
+func configDir() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+
+		dir = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(dir, "adbtuifm")
+}
+
+func configPath(name string) string {
+	dir := configDir()
+	if dir == "" {
+		return ""
+	}
+
+	return filepath.Join(dir, name)
+}
+
+func loadConfig() error {
+	cpath := configPath("config.json")
+
+	if err := readConfig(cpath); err != nil {
+		return err
+	}
+
+	if config.LSColors == nil || *config.LSColors {
+		loadLSColors(os.Getenv("LS_COLORS"))
+	}
+
+	for _, o := range config.Openers {
+		if o.Command == "" {
+			return fmt.Errorf("%s: opener '%s' has no command", cpath, o.Name)
+		}
+	}
+
+	if config.StagingDir != "" && !filepath.IsAbs(config.StagingDir) {
+		return fmt.Errorf("%s: staging_dir must be an absolute path", cpath)
+	}
+
+	if err := loadKeyBindings(config.Keys); err != nil {
+		return fmt.Errorf("%s: %s", cpath, err.Error())
+	}
+
+	if err := loadTheme(config.Theme); err != nil {
+		return fmt.Errorf("%s: %s", cpath, err.Error())
+	}
+
+	return nil
+}
+
+func writeConfigFile(cpath string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(cpath), 0700); err != nil {
+		return err
+	}
+
+	tmp := cpath + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, cpath)
+}
+
+func readConfig(cpath string) error {
+	if cpath == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(cpath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	if err = json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("%s: %s", cpath, err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,531 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/darkhz/tview"
+	"github.com/gdamore/tcell/v2"
+	adb "github.com/zach-klippenstein/goadb"
+)
+
+type duEntry struct {
+	path  string
+	size  int64
+	entry *adb.DirEntry
+}
+
+type duResult struct {
+	entries []duEntry
+	total   int64
+	done    bool
+	err     error
+}
+
+type diskUsage struct {
+	pane *dirPane
+	mode ifaceMode
+	path string
+
+	cache  map[string]*duResult
+	cancel context.CancelFunc
+	lock   sync.Mutex
+
+	table *tview.Table
+	title *tview.TextView
+}
+
+const duBarWidth = 20
+
+func (p *dirPane) showDiskUsage() {
+	if p.mode == mAdb && !checkAdb() {
+		return
+	}
+
+	du := &diskUsage{
+		pane:  p,
+		mode:  p.mode,
+		cache: make(map[string]*duResult),
+		table: tview.NewTable(),
+		title: newTextView(),
+	}
+
+	du.setupView()
+	du.open(filepath.Clean(p.getPath()), "")
+}
+
+// open shows the disk usage of the children of path, computing
+// it if needed, and highlights the entry named cursor.
+func (du *diskUsage) open(path, cursor string) {
+	if du.cancel != nil {
+		du.cancel()
+	}
+
+	du.path = path
+
+	du.lock.Lock()
+	res, ok := du.cache[path]
+	if !ok || (res.done && res.err != nil) {
+		res = &duResult{}
+		du.cache[path] = res
+		ok = false
+	}
+	du.lock.Unlock()
+
+	if !ok || !res.done {
+		ctx, cancel := context.WithCancel(context.Background())
+		du.cancel = cancel
+
+		go du.compute(ctx, path, res)
+	}
+
+	du.render(cursor)
+}
+
+func (du *diskUsage) compute(ctx context.Context, path string, res *duResult) {
+	var err error
+
+	switch du.mode {
+	case mAdb:
+		err = du.adbCompute(ctx, path, res)
+
+	case mLocal:
+		err = du.localCompute(ctx, path, res)
+	}
+
+	if ctx.Err() != nil {
+		du.lock.Lock()
+		if du.cache[path] == res {
+			delete(du.cache, path)
+		}
+		du.lock.Unlock()
+
+		return
+	}
+
+	du.lock.Lock()
+	res.err = err
+	res.done = true
+	du.lock.Unlock()
+
+	go app.QueueUpdateDraw(func() {
+		if du.path == path {
+			du.render("")
+		}
+	})
+}
+
+func (du *diskUsage) adbCompute(ctx context.Context, path string, res *duResult) error {
+	var entries []duEntry
+	var total int64
+
+	device, err := getAdb()
+	if err != nil {
+		return err
+	}
+
+	su := du.pane.getSu()
+	cmd := wrapAdbCommand(path, fmt.Sprintf("du -a -d 1 -k %s 2>/dev/null", shellQuote(findPath(path))), su)
+
+	logIndex := startLog(fmt.Sprintf("shell %s", cmd))
+
+	out, err := exec.CommandContext(ctx, "adb", "shell", cmd).Output()
+	if err != nil && len(out) == 0 {
+		updateLog(logIndex, err.Error(), ctx.Err() == nil)
+		return err
+	}
+
+	// The types of the entries are read with one listing of path,
+	// rather than a stat of each entry.
+	types := make(map[string]*adb.DirEntry)
+
+	if list, err := adbReadDir(device, path, su); err == nil {
+		for _, entry := range list {
+			types[entry.Name] = entry
+		}
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.SplitN(strings.TrimRight(line, "\r"), "\t", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		size *= 1024
+
+		epath := filepath.Clean(fields[1])
+		if epath == path {
+			total = size
+			continue
+		}
+
+		entry, ok := types[filepath.Base(epath)]
+		if !ok {
+			entry = &adb.DirEntry{Name: filepath.Base(epath)}
+		}
+
+		entries = append(entries, duEntry{epath, size, entry})
+	}
+
+	updateLog(logIndex, fmt.Sprintf("%d entries", len(entries)), false)
+
+	du.lock.Lock()
+	res.entries = entries
+	res.total = total
+	du.lock.Unlock()
+
+	return nil
+}
+
+// localCompute sums the disk usage of each child of path,
+// updating the view as each child is completed.
+func (du *diskUsage) localCompute(ctx context.Context, path string, res *duResult) error {
+	list, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range list {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			continue
+		}
+
+		cpath := filepath.Join(path, d.Name())
+
+		size := getDiskSize(info)
+		if d.IsDir() {
+			filepath.WalkDir(cpath, func(p string, d fs.DirEntry, err error) error {
+				if ctx.Err() != nil {
+					return filepath.SkipAll
+				}
+
+				if err != nil || p == cpath {
+					return nil
+				}
+
+				if info, err := d.Info(); err == nil {
+					size += getDiskSize(info)
+				}
+
+				return nil
+			})
+		}
+
+		entry := &adb.DirEntry{
+			Name:       d.Name(),
+			Mode:       info.Mode(),
+			ModifiedAt: info.ModTime(),
+		}
+
+		du.lock.Lock()
+		res.entries = append(res.entries, duEntry{cpath, size, entry})
+		res.total += size
+		du.lock.Unlock()
+
+		go app.QueueUpdateDraw(func() {
+			if du.path == path {
+				du.render("")
+			}
+		})
+	}
+
+	return nil
+}
+
+func getDiskSize(info fs.FileInfo) int64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int64(st.Blocks) * 512
+	}
+
+	return info.Size()
+}
+
+// render redraws the entries of the current directory, largest first.
+func (du *diskUsage) render(cursor string) {
+	du.lock.Lock()
+	res, ok := du.cache[du.path]
+	if !ok {
+		du.lock.Unlock()
+		return
+	}
+	entries := append([]duEntry{}, res.entries...)
+	total, done, err := res.total, res.done, res.err
+	du.lock.Unlock()
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].size > entries[j].size
+	})
+
+	if cursor == "" {
+		row, _ := du.table.GetSelection()
+		if cell := du.table.GetCell(row, 3); cell != nil {
+			if e, ok := cell.GetReference().(duEntry); ok {
+				cursor = e.path
+			}
+		}
+	} else {
+		cursor = filepath.Join(du.path, cursor)
+	}
+
+	status := "calculating"
+	switch {
+	case err != nil:
+		status = err.Error()
+
+	case done:
+		status = fmt.Sprintf("%d entries", len(entries))
+	}
+
+	du.title.SetText(fmt.Sprintf(
+		"%sDisk usage (%s): %s: %s (%s)",
+		getStyle("title").tag(),
+		du.mode.String(),
+		tview.Escape(du.path),
+		formatFileSize(total),
+		tview.Escape(status),
+	))
+
+	du.table.Clear()
+
+	var pos int
+
+	for row, e := range entries {
+		var percent float64
+
+		if total > 0 {
+			percent = float64(e.size) * 100 / float64(total)
+		}
+
+		fill := int(percent * duBarWidth / 100)
+		bar := strings.Repeat("█", fill) + strings.Repeat("░", duBarWidth-fill)
+
+		name := e.entry.Name
+		if e.entry.Mode.IsDir() {
+			name += "/"
+		}
+
+		sel := checkmsel(e.path)
+
+		du.table.SetCell(row, 0, tview.NewTableCell(formatFileSize(e.size)+" ").
+			SetAlign(tview.AlignRight).
+			SetStyle(setEntryColor(1, sel, "", e.entry).style()))
+
+		du.table.SetCell(row, 1, tview.NewTableCell(fmt.Sprintf("%5.1f%% ", percent)).
+			SetAlign(tview.AlignRight).
+			SetStyle(setEntryColor(1, sel, "", e.entry).style()))
+
+		du.table.SetCell(row, 2, tview.NewTableCell(bar+" ").
+			SetStyle(getStyle("progress").style()))
+
+		du.table.SetCell(row, 3, tview.NewTableCell(tview.Escape(name)).
+			SetExpansion(1).
+			SetReference(e).
+			SetStyle(setEntryColor(0, sel, getEntryPerms(e.entry), e.entry).style()))
+
+		if e.path == cursor {
+			pos = row
+		}
+	}
+
+	du.table.Select(pos, 0)
+}
+
+func (du *diskUsage) selected() (duEntry, bool) {
+	row, _ := du.table.GetSelection()
+
+	e, ok := du.table.GetCell(row, 3).GetReference().(duEntry)
+
+	return e, ok
+}
+
+func (du *diskUsage) toggle() {
+	e, ok := du.selected()
+	if !ok {
+		return
+	}
+
+	if checkmsel(e.path) {
+		delmsel(e.path)
+	} else {
+		addmsel(e.path, du.mode)
+		selected = true
+	}
+
+	row, _ := du.table.GetSelection()
+
+	du.render("")
+
+	if row+1 < du.table.GetRowCount() {
+		du.table.Select(row+1, 0)
+	}
+}
+
+// confirmDelete deletes the selected entries of the current directory,
+// and recomputes its disk usage once they are deleted.
+func (du *diskUsage) confirmDelete() {
+	var mselect []selection
+
+	path := du.path
+
+	du.lock.Lock()
+	if res, ok := du.cache[path]; ok {
+		for _, e := range res.entries {
+			if checkmsel(e.path) {
+				mselect = append(mselect, selection{e.path, du.mode})
+			}
+		}
+	}
+	du.lock.Unlock()
+
+	if mselect == nil {
+		showInfoMsg("No items selected. Use " + getKeyNames("du-select") + " to select items first.")
+		return
+	}
+
+	input := getStatusInput(fmt.Sprintf("Delete %d selected item(s) (Y/n)?", len(mselect)), true)
+
+	exit := func() {
+		statuspgs.SwitchToPage("statusmsg")
+		app.SetFocus(du.table)
+	}
+
+	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEnter:
+			text := strings.ToLower(input.GetText())
+			if text != "" && text != "y" {
+				exit()
+				return nil
+			}
+
+			for _, sel := range mselect {
+				delmsel(sel.path)
+			}
+
+			go func() {
+				_, err := startOperation(du.pane, du.pane, opDelete, false, mselect)
+				if err != nil {
+					showErrorMsg(err, false)
+				}
+
+				go app.QueueUpdateDraw(func() {
+					du.lock.Lock()
+					for p := range du.cache {
+						if p == path || strings.HasPrefix(path, p+"/") || p == "/" {
+							delete(du.cache, p)
+						}
+					}
+					du.lock.Unlock()
+
+					if du.path == path {
+						du.open(path, "")
+					}
+				})
+			}()
+
+			exit()
+			showInfoMsg("Deleting items")
+
+			return nil
+
+		case tcell.KeyEscape:
+			exit()
+			return nil
+		}
+
+		return event
+	})
+
+	statuspgs.AddAndSwitchToPage("confirm", input, true)
+	app.SetFocus(input)
+}
+
+func (du *diskUsage) setupView() {
+	flex := tview.NewFlex().
+		AddItem(du.title, 1, 0, false).
+		AddItem(du.table, 0, 1, true).
+		AddItem(statuspgs, 1, 0, false).
+		SetDirection(tview.FlexRow)
+
+	exit := func() {
+		if du.cancel != nil {
+			du.cancel()
+		}
+
+		pages.SwitchToPage("main")
+		pages.RemovePage("diskusage")
+
+		app.SetFocus(du.pane.table)
+
+		t := tabs[tabPos]
+		t.selPane.reselect(false)
+		t.auxPane.reselect(false)
+	}
+
+	du.table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch getKeyAction(kDiskUsage, event) {
+		case "du-open":
+			if e, ok := du.selected(); ok && e.entry.Mode.IsDir() {
+				du.open(e.path, "")
+			}
+
+			return nil
+
+		case "du-back":
+			if du.path != "/" {
+				du.open(filepath.Dir(du.path), filepath.Base(du.path))
+			}
+
+			return nil
+
+		case "du-select":
+			du.toggle()
+			return nil
+
+		case "du-delete":
+			du.confirmDelete()
+			return nil
+
+		case "du-refresh":
+			du.lock.Lock()
+			delete(du.cache, du.path)
+			du.lock.Unlock()
+
+			du.open(du.path, "")
+
+			return nil
+
+		case "du-exit":
+			exit()
+			return nil
+		}
+
+		return event
+	})
+
+	du.table.SetSelectable(true, false)
+	du.table.SetBackgroundColor(tcell.ColorDefault)
+	du.table.SetSelectedStyle(getStyle("cursor").style())
+
+	pages.AddAndSwitchToPage("diskusage", flex, true)
+	app.SetFocus(du.table)
+}
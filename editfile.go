@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/darkhz/tview"
+	"github.com/gdamore/tcell/v2"
+)
+
+type editFile struct {
+	mode ifaceMode
+	path string
+	su   bool
+	perm os.FileMode
+	data []byte
+	crlf bool
+}
+
+const maxEditSize = 1024 * 1024
+
+var errEditConflict = errors.New("file changed since it was opened")
+
+// loadEditFile reads a text file for editing. Symbolic links are
+// resolved, so that saving replaces the target instead of the link.
+func loadEditFile(mode ifaceMode, path string, su bool) (*editFile, error) {
+	var size int64
+
+	f := &editFile{mode: mode, path: path, su: su}
+
+	switch mode {
+	case mAdb:
+		device, err := getAdb()
+		if err != nil {
+			return nil, err
+		}
+
+		if out, err := runAdbShellCommand(device, wrapAdbCommand(path, "readlink -f "+shellQuote(path), su)); err == nil {
+			if target := strings.TrimSpace(out); strings.HasPrefix(target, "/") {
+				f.path = target
+			}
+		}
+
+		stat, err := adbStat(device, f.path, su)
+		if err != nil {
+			return nil, err
+		}
+
+		f.perm = stat.Mode.Perm()
+		size = int64(uint32(stat.Size))
+
+	case mLocal:
+		if target, err := filepath.EvalSymlinks(path); err == nil {
+			f.path = target
+		}
+
+		info, err := os.Stat(f.path)
+		if err != nil {
+			return nil, err
+		}
+
+		f.perm = info.Mode().Perm()
+		size = info.Size()
+	}
+
+	if size > maxEditSize {
+		return nil, fmt.Errorf("'%s' is too large to edit (%s)", filepath.Base(path), formatFileSize(size))
+	}
+
+	data, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		return nil, fmt.Errorf("'%s' is not a text file", filepath.Base(path))
+	}
+
+	f.data = data
+	f.crlf = bytes.Contains(data, []byte("\r\n"))
+
+	return f, nil
+}
+
+func (f *editFile) read() ([]byte, error) {
+	var rd io.ReadCloser
+
+	switch f.mode {
+	case mAdb:
+		device, err := getAdb()
+		if err != nil {
+			return nil, err
+		}
+
+		rd, err = adbOpenRead(context.Background(), device, f.path, f.su)
+		if err != nil {
+			return nil, err
+		}
+
+	case mLocal:
+		file, err := os.Open(f.path)
+		if err != nil {
+			return nil, err
+		}
+
+		rd = file
+	}
+	defer rd.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rd, maxEditSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > maxEditSize {
+		return nil, fmt.Errorf("'%s' is too large to edit", filepath.Base(f.path))
+	}
+
+	return data, nil
+}
+
+func (f *editFile) getText() string {
+	return strings.ReplaceAll(string(f.data), "\r\n", "\n")
+}
+
+// save writes text to a temporary file next to the file, and renames
+// it over the file. Unless force is set, the file is first checked for
+// changes made since it was opened or last saved.
+func (f *editFile) save(text string, force bool) error {
+	if f.crlf {
+		text = strings.ReplaceAll(text, "\n", "\r\n")
+	}
+
+	data := []byte(text)
+
+	if !force {
+		if cur, err := f.read(); err == nil && !bytes.Equal(cur, f.data) {
+			return errEditConflict
+		}
+	}
+
+	dir, base := filepath.Dir(f.path), filepath.Base(f.path)
+
+	switch f.mode {
+	case mAdb:
+		device, err := getAdb()
+		if err != nil {
+			return err
+		}
+
+		tmp := filepath.Join(dir, "."+base+"."+strconv.FormatInt(time.Now().UnixNano(), 36)+".tmp")
+
+		w, err := adbOpenWrite(device, tmp, f.perm, f.su)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(data)
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+
+		if err != nil {
+			runAdbShellCommand(device, wrapAdbCommand(tmp, "rm -f "+shellQuote(tmp), f.su))
+			return err
+		}
+
+		cmd := fmt.Sprintf("mv -f %s %s 2>&1 || rm -f %s", shellQuote(tmp), shellQuote(f.path), shellQuote(tmp))
+
+		// Files written with su are owned by root, so keep the owner of the original.
+		if f.su {
+			cmd = fmt.Sprintf("chown $(stat -c %%u:%%g %s) %s 2>/dev/null; chmod %o %s 2>/dev/null; ",
+				shellQuote(f.path), shellQuote(tmp), f.perm, shellQuote(tmp)) + cmd
+		}
+
+		out, err := runAdbShellCommand(device, wrapAdbCommand(f.path, cmd, f.su))
+		if err != nil {
+			return err
+		}
+
+		if out = strings.TrimSpace(out); out != "" {
+			return errors.New(out)
+		}
+
+	case mLocal:
+		tmp, err := os.CreateTemp(dir, "."+base+".*.tmp")
+		if err != nil {
+			return err
+		}
+
+		_, err = tmp.Write(data)
+		if err == nil {
+			err = tmp.Chmod(f.perm)
+		}
+
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+
+		if err == nil {
+			err = os.Rename(tmp.Name(), f.path)
+		}
+
+		if err != nil {
+			os.Remove(tmp.Name())
+			return err
+		}
+	}
+
+	f.data = data
+
+	return nil
+}
+
+// editFileHandler opens the highlighted file in the editor page,
+// or in the pager if readonly is set.
+func (p *dirPane) editFileHandler(readonly bool) {
+	p.updateRef(false)
+
+	if p.entry == nil || p.entry.Name == ".." || p.entry.Mode.IsDir() {
+		return
+	}
+
+	if p.mode == mAdb && !checkAdb() {
+		return
+	}
+
+	mode := p.mode
+	name := p.entry.Name
+	path := filepath.Join(p.getPath(), name)
+	su := p.getSu()
+
+	showInfoMsg("Loading " + name)
+
+	go func() {
+		f, err := loadEditFile(mode, path, su)
+		if err != nil {
+			showErrorMsg(err, false)
+			return
+		}
+
+		go app.QueueUpdateDraw(func() {
+			sendMessage(message{"", false})
+			p.showEditor(f, readonly)
+		})
+	}()
+}
+
+func (p *dirPane) showEditor(f *editFile, readonly bool) {
+	var saved bool
+
+	editor := newTextEditor(f.getText(), readonly)
+	title := newTextView()
+
+	flex := tview.NewFlex().
+		AddItem(title, 1, 0, false).
+		AddItem(editor, 0, 1, true).
+		AddItem(statuspgs, 1, 0, false).
+		SetDirection(tview.FlexRow)
+
+	updateTitle := func() {
+		var text string
+
+		if editor.readonly {
+			text = fmt.Sprintf("View (%s): %s (%s to edit, %s to close)",
+				f.mode.String(), f.path, getKeyNames("pager-edit"), getKeyNames("pager-exit"))
+		} else {
+			modified := ""
+			if editor.modified {
+				modified = " [modified]"
+			}
+
+			text = fmt.Sprintf("Edit (%s): %s%s, line %d, column %d (%s to save, %s to close)",
+				f.mode.String(), f.path, modified, editor.row+1, editor.col+1,
+				getKeyNames("editor-save"), getKeyNames("editor-exit"))
+		}
+
+		title.SetText(getStyle("title").tag() + tview.Escape(text))
+	}
+
+	editor.changed = updateTitle
+
+	exit := func() {
+		pages.SwitchToPage("main")
+		pages.RemovePage("editor")
+
+		app.SetFocus(p.table)
+
+		if saved {
+			p.ChangeDir(false, false)
+		}
+	}
+
+	confirm := func(msg string, yes func()) {
+		input := getStatusInput(msg, true)
+
+		input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+			switch event.Key() {
+			case tcell.KeyEnter:
+				statuspgs.SwitchToPage("statusmsg")
+				app.SetFocus(editor)
+
+				if strings.ToLower(input.GetText()) == "y" {
+					yes()
+				}
+
+				return nil
+
+			case tcell.KeyEscape:
+				statuspgs.SwitchToPage("statusmsg")
+				app.SetFocus(editor)
+
+				return nil
+			}
+
+			return event
+		})
+
+		statuspgs.AddAndSwitchToPage("confirm", input, true)
+		app.SetFocus(input)
+	}
+
+	var save func(force bool)
+	save = func(force bool) {
+		text := editor.getText()
+
+		showInfoMsg("Saving " + filepath.Base(f.path))
+
+		go func() {
+			err := f.save(text, force)
+
+			go app.QueueUpdateDraw(func() {
+				switch {
+				case err == errEditConflict:
+					sendMessage(message{"", false})
+					confirm("The file was changed since it was opened. Overwrite it (y/N)?", func() {
+						save(true)
+					})
+
+				case err != nil:
+					showErrorMsg(fmt.Errorf("Unable to save '%s': %s", filepath.Base(f.path), err.Error()), false)
+
+				default:
+					saved = true
+					if editor.getText() == text {
+						editor.modified = false
+					}
+
+					updateTitle()
+					showInfoMsg("Saved " + f.path)
+				}
+			})
+		}()
+	}
+
+	editor.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if editor.readonly {
+			switch getKeyAction(kPager, event) {
+			case "pager-edit":
+				editor.readonly = false
+				editor.row = min(editor.top, len(editor.lines)-1)
+				editor.col = 0
+
+				updateTitle()
+
+				return nil
+
+			case "pager-exit":
+				exit()
+				return nil
+			}
+
+			return event
+		}
+
+		switch getKeyAction(kEditor, event) {
+		case "editor-save":
+			save(false)
+			return nil
+
+		case "editor-exit":
+			if !editor.modified {
+				exit()
+				return nil
+			}
+
+			confirm("Discard unsaved changes (y/N)?", exit)
+
+			return nil
+		}
+
+		return event
+	})
+
+	updateTitle()
+
+	pages.AddAndSwitchToPage("editor", flex, true)
+	app.SetFocus(editor)
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/darkhz/tview"
+	"github.com/gdamore/tcell/v2"
+)
+
+// textEditor is a minimal editor for plain text, with line numbers
+// and horizontal scrolling. In readonly mode it acts as a pager.
+type textEditor struct {
+	*tview.Box
+
+	lines     [][]rune
+	row, col  int
+	top, left int
+	height    int
+	readonly  bool
+	modified  bool
+
+	changed func()
+}
+
+const editorTabWidth = 4
+
+func newTextEditor(text string, readonly bool) *textEditor {
+	e := &textEditor{
+		Box:      tview.NewBox(),
+		readonly: readonly,
+	}
+
+	e.SetBackgroundColor(tcell.ColorDefault)
+
+	for _, line := range strings.Split(text, "\n") {
+		e.lines = append(e.lines, []rune(line))
+	}
+
+	return e
+}
+
+func (e *textEditor) getText() string {
+	lines := make([]string, len(e.lines))
+
+	for i, line := range e.lines {
+		lines[i] = string(line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// runeWidth returns the width of r when drawn at the visual column x.
+func runeWidth(r rune, x int) int {
+	if r == '\t' {
+		return editorTabWidth - x%editorTabWidth
+	}
+
+	if w := tview.TaggedStringWidth(string(r)); w > 0 || r == '[' {
+		return max(w, 1)
+	}
+
+	return 1
+}
+
+func (e *textEditor) visualCol(row, col int) int {
+	var x int
+
+	for _, r := range e.lines[row][:col] {
+		x += runeWidth(r, x)
+	}
+
+	return x
+}
+
+func (e *textEditor) Draw(screen tcell.Screen) {
+	e.DrawForSubclass(screen, e)
+
+	x, y, width, height := e.GetInnerRect()
+
+	gutter := len(strconv.Itoa(len(e.lines))) + 1
+	textWidth := width - gutter
+
+	e.height = height
+	if textWidth <= 0 || height <= 0 {
+		return
+	}
+
+	e.scroll(textWidth, height)
+
+	numStyle := getStyle("column").style()
+	textStyle := tcell.StyleDefault.Foreground(tcell.ColorDefault).Background(tcell.ColorDefault)
+
+	for i := 0; i < height && e.top+i < len(e.lines); i++ {
+		num := fmt.Sprintf("%*d ", gutter-1, e.top+i+1)
+		for j, r := range num {
+			screen.SetContent(x+j, y+i, r, nil, numStyle)
+		}
+
+		var vx int
+
+		for _, r := range e.lines[e.top+i] {
+			w := runeWidth(r, vx)
+
+			if vx >= e.left && vx+w-e.left <= textWidth {
+				sx := x + gutter + vx - e.left
+
+				if r == '\t' {
+					for k := 0; k < w; k++ {
+						screen.SetContent(sx+k, y+i, ' ', nil, textStyle)
+					}
+				} else {
+					screen.SetContent(sx, y+i, r, nil, textStyle)
+				}
+			}
+
+			vx += w
+			if vx-e.left >= textWidth {
+				break
+			}
+		}
+	}
+
+	if !e.readonly && e.HasFocus() {
+		cx := e.visualCol(e.row, e.col) - e.left
+		screen.ShowCursor(x+gutter+cx, y+e.row-e.top)
+	}
+}
+
+// scroll keeps the cursor within the view while editing,
+// and the view within the text while paging.
+func (e *textEditor) scroll(width, height int) {
+	if e.readonly {
+		e.top = max(min(e.top, len(e.lines)-height), 0)
+		e.left = max(e.left, 0)
+
+		return
+	}
+
+	if e.row < e.top {
+		e.top = e.row
+	}
+
+	if e.row >= e.top+height {
+		e.top = e.row - height + 1
+	}
+
+	cx := e.visualCol(e.row, e.col)
+
+	if cx < e.left {
+		e.left = cx
+	}
+
+	if cx >= e.left+width {
+		e.left = cx - width + 1
+	}
+}
+
+func (e *textEditor) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return e.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		if e.readonly {
+			e.pageKey(event)
+		} else {
+			e.editKey(event)
+		}
+
+		if e.changed != nil {
+			e.changed()
+		}
+	})
+}
+
+func (e *textEditor) pageKey(event *tcell.EventKey) {
+	switch event.Key() {
+	case tcell.KeyUp:
+		e.top--
+
+	case tcell.KeyDown, tcell.KeyEnter:
+		e.top++
+
+	case tcell.KeyPgUp:
+		e.top -= e.height
+
+	case tcell.KeyPgDn:
+		e.top += e.height
+
+	case tcell.KeyLeft:
+		e.left -= editorTabWidth
+
+	case tcell.KeyRight:
+		e.left += editorTabWidth
+
+	case tcell.KeyHome:
+		e.top, e.left = 0, 0
+
+	case tcell.KeyEnd:
+		e.top = len(e.lines)
+
+	case tcell.KeyRune:
+		if event.Rune() == ' ' {
+			e.top += e.height
+		}
+	}
+}
+
+func (e *textEditor) editKey(event *tcell.EventKey) {
+	line := e.lines[e.row]
+
+	switch event.Key() {
+	case tcell.KeyUp:
+		e.moveRow(-1)
+
+	case tcell.KeyDown:
+		e.moveRow(1)
+
+	case tcell.KeyPgUp:
+		e.moveRow(-e.height)
+
+	case tcell.KeyPgDn:
+		e.moveRow(e.height)
+
+	case tcell.KeyLeft:
+		switch {
+		case e.col > 0:
+			e.col--
+
+		case e.row > 0:
+			e.row--
+			e.col = len(e.lines[e.row])
+		}
+
+	case tcell.KeyRight:
+		switch {
+		case e.col < len(line):
+			e.col++
+
+		case e.row < len(e.lines)-1:
+			e.row++
+			e.col = 0
+		}
+
+	case tcell.KeyHome:
+		e.col = 0
+
+	case tcell.KeyEnd:
+		e.col = len(line)
+
+	case tcell.KeyEnter:
+		rest := append([]rune{}, line[e.col:]...)
+
+		e.lines[e.row] = line[:e.col]
+		e.lines = append(e.lines[:e.row+1], append([][]rune{rest}, e.lines[e.row+1:]...)...)
+		e.row++
+		e.col = 0
+		e.modified = true
+
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		switch {
+		case e.col > 0:
+			e.lines[e.row] = append(line[:e.col-1], line[e.col:]...)
+			e.col--
+			e.modified = true
+
+		case e.row > 0:
+			prev := e.lines[e.row-1]
+
+			e.col = len(prev)
+			e.lines[e.row-1] = append(prev, line...)
+			e.lines = append(e.lines[:e.row], e.lines[e.row+1:]...)
+			e.row--
+			e.modified = true
+		}
+
+	case tcell.KeyDelete:
+		switch {
+		case e.col < len(line):
+			e.lines[e.row] = append(line[:e.col], line[e.col+1:]...)
+			e.modified = true
+
+		case e.row < len(e.lines)-1:
+			e.lines[e.row] = append(line, e.lines[e.row+1]...)
+			e.lines = append(e.lines[:e.row+1], e.lines[e.row+2:]...)
+			e.modified = true
+		}
+
+	case tcell.KeyTab:
+		e.insert('\t')
+
+	case tcell.KeyRune:
+		e.insert(event.Rune())
+	}
+}
+
+func (e *textEditor) insert(r rune) {
+	line := e.lines[e.row]
+
+	line = append(line[:e.col], append([]rune{r}, line[e.col:]...)...)
+
+	e.lines[e.row] = line
+	e.col++
+	e.modified = true
+}
+
+func (e *textEditor) moveRow(n int) {
+	e.row = max(min(e.row+n, len(e.lines)-1), 0)
+	e.col = min(e.col, len(e.lines[e.row]))
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/darkhz/tview"
+	"github.com/gdamore/tcell/v2"
+)
+
+type fileInfoField struct {
+	name  string
+	value string
+}
+
+const infoTimeFormat = "2006-01-02 15:04:05 -0700"
+
+// getMimeType guesses the MIME type of a file from its first bytes,
+// falling back to its extension if the content is not recognized.
+func getMimeType(name string, head []byte) string {
+	mtype := http.DetectContentType(head)
+
+	if strings.HasPrefix(mtype, "application/octet-stream") || strings.HasPrefix(mtype, "text/plain") {
+		if ext := mime.TypeByExtension(filepath.Ext(name)); ext != "" {
+			return ext
+		}
+	}
+
+	return mtype
+}
+
+func getAdbFileInfo(ctx context.Context, path string, su bool) ([]fileInfoField, error) {
+	var fields []fileInfoField
+
+	device, err := getAdb()
+	if err != nil {
+		return nil, err
+	}
+
+	qpath := shellQuote(path)
+
+	out, err := runAdbShellCommand(device, wrapAdbCommand(path, "stat -c '%s|%A|%a|%U|%u|%G|%g|%F|%h|%i|%x|%y|%z' "+qpath, su))
+	if err != nil {
+		return nil, err
+	}
+
+	st := strings.Split(strings.TrimSpace(out), "|")
+	if len(st) < 13 {
+		return nil, fmt.Errorf("Cannot stat %s: %s", path, strings.TrimSpace(out))
+	}
+
+	fields = append(fields,
+		fileInfoField{"Path", path},
+		fileInfoField{"Type", st[7]},
+		fileInfoField{"Size", st[0] + " bytes"},
+		fileInfoField{"Mode", st[1] + " (" + st[2] + ")"},
+		fileInfoField{"Owner", st[3] + " (" + st[4] + ")"},
+		fileInfoField{"Group", st[5] + " (" + st[6] + ")"},
+		fileInfoField{"Links", st[8]},
+		fileInfoField{"Inode", st[9]},
+		fileInfoField{"Accessed", st[10]},
+		fileInfoField{"Modified", st[11]},
+		fileInfoField{"Changed", st[12]},
+	)
+
+	if label, err := runAdbShellCommand(device, wrapAdbCommand(path, "ls -Zd "+qpath, su)); err == nil {
+		if f := strings.Fields(label); len(f) > 1 {
+			fields = append(fields, fileInfoField{"SELinux context", f[0]})
+		}
+	}
+
+	isdir := strings.Contains(st[7], "directory")
+
+	if strings.Contains(st[7], "symbolic link") {
+		target, _ := runAdbShellCommand(device, wrapAdbCommand(path, "readlink "+qpath, su))
+		fields = append(fields, fileInfoField{"Link target", strings.TrimSpace(target)})
+
+		isdir = isAdbSymDir(filepath.Dir(path)+"/", filepath.Base(path), su)
+	}
+
+	if isdir {
+		fpath := shellQuote(findPath(path))
+		cmd := fmt.Sprintf(
+			"find %s -type f 2>/dev/null | wc -l; find %s -mindepth 1 -type d 2>/dev/null | wc -l; du -sk %s 2>/dev/null",
+			fpath, fpath, fpath,
+		)
+
+		if out, err := runAdbShellCommandContext(ctx, wrapAdbCommand(path, cmd, su)); err == nil {
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			if len(lines) >= 3 {
+				kb, _ := strconv.ParseInt(strings.Fields(lines[2])[0], 10, 64)
+
+				fields = append(fields,
+					fileInfoField{"Contents", fmt.Sprintf(
+						"%s files, %s directories",
+						strings.TrimSpace(lines[0]), strings.TrimSpace(lines[1]),
+					)},
+					fileInfoField{"Disk usage", formatFileSize(kb * 1024)},
+				)
+			}
+		}
+
+		return fields, nil
+	}
+
+	if desc, err := runAdbShellCommand(device, wrapAdbCommand(path, "file "+qpath, su)); err == nil {
+		desc = strings.TrimPrefix(strings.TrimSpace(desc), path+": ")
+		fields = append(fields, fileInfoField{"File type", desc})
+	}
+
+	if rd, err := adbOpenRead(ctx, device, path, su); err == nil {
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(rd, head)
+		rd.Close()
+
+		fields = append(fields, fileInfoField{"MIME type", getMimeType(path, head[:n])})
+	}
+
+	return fields, nil
+}
+
+func getLocalFileInfo(ctx context.Context, path string) ([]fileInfoField, error) {
+	var fields []fileInfoField
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ftype := "regular file"
+	switch mode := info.Mode(); {
+	case mode.IsDir():
+		ftype = "directory"
+
+	case mode&os.ModeSymlink != 0:
+		ftype = "symbolic link"
+
+	case mode&os.ModeNamedPipe != 0:
+		ftype = "fifo"
+
+	case mode&os.ModeSocket != 0:
+		ftype = "socket"
+
+	case mode&os.ModeDevice != 0:
+		ftype = "device"
+	}
+
+	fields = append(fields,
+		fileInfoField{"Path", path},
+		fileInfoField{"Type", ftype},
+		fileInfoField{"Size", strconv.FormatInt(info.Size(), 10) + " bytes"},
+		fileInfoField{"Mode", info.Mode().String() + " (" + strconv.FormatUint(uint64(info.Mode().Perm()), 8) + ")"},
+	)
+
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		uid := strconv.FormatUint(uint64(st.Uid), 10)
+		gid := strconv.FormatUint(uint64(st.Gid), 10)
+
+		owner, group := uid, gid
+
+		if u, err := user.LookupId(uid); err == nil {
+			owner = u.Username
+		}
+
+		if g, err := user.LookupGroupId(gid); err == nil {
+			group = g.Name
+		}
+
+		fields = append(fields,
+			fileInfoField{"Owner", owner + " (" + uid + ")"},
+			fileInfoField{"Group", group + " (" + gid + ")"},
+			fileInfoField{"Links", strconv.FormatUint(uint64(st.Nlink), 10)},
+			fileInfoField{"Inode", strconv.FormatUint(uint64(st.Ino), 10)},
+		)
+	}
+
+	if atime, ctime, ok := statTimes(info); ok {
+		fields = append(fields, fileInfoField{"Accessed", atime.Format(infoTimeFormat)})
+		fields = append(fields, fileInfoField{"Modified", info.ModTime().Format(infoTimeFormat)})
+		fields = append(fields, fileInfoField{"Changed", ctime.Format(infoTimeFormat)})
+	} else {
+		fields = append(fields, fileInfoField{"Modified", info.ModTime().Format(infoTimeFormat)})
+	}
+
+	if ctx := getSELinuxContext(path); ctx != "" {
+		fields = append(fields, fileInfoField{"SELinux context", ctx})
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, _ := os.Readlink(path)
+		fields = append(fields, fileInfoField{"Link target", target})
+
+		if tinfo, err := os.Stat(path); err == nil {
+			info = tinfo
+		}
+	}
+
+	if info.IsDir() {
+		var files, dirs int
+		var size int64
+
+		filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return filepath.SkipAll
+			}
+
+			if err != nil || p == path {
+				return nil
+			}
+
+			if d.IsDir() {
+				dirs++
+			} else {
+				files++
+			}
+
+			if dinfo, err := d.Info(); err == nil {
+				size += getDiskSize(dinfo)
+			}
+
+			return nil
+		})
+
+		fields = append(fields,
+			fileInfoField{"Contents", fmt.Sprintf("%d files, %d directories", files, dirs)},
+			fileInfoField{"Disk usage", formatFileSize(size)},
+		)
+
+		return fields, nil
+	}
+
+	if _, err := exec.LookPath("file"); err == nil {
+		if out, err := exec.Command("file", "-b", path).Output(); err == nil {
+			fields = append(fields, fileInfoField{"File type", strings.TrimSpace(string(out))})
+		}
+
+		if out, err := exec.Command("file", "-b", "--mime-type", path).Output(); err == nil {
+			fields = append(fields, fileInfoField{"MIME type", strings.TrimSpace(string(out))})
+			return fields, nil
+		}
+	}
+
+	if f, err := os.Open(path); err == nil {
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(f, head)
+		f.Close()
+
+		fields = append(fields, fileInfoField{"MIME type", getMimeType(path, head[:n])})
+	}
+
+	return fields, nil
+}
+
+func (p *dirPane) showFileInfo() {
+	p.updateRef(false)
+
+	if p.entry == nil || p.entry.Name == ".." {
+		return
+	}
+
+	if p.mode == mAdb && !checkAdb() {
+		return
+	}
+
+	mode := p.mode
+	path := filepath.Join(p.getPath(), p.entry.Name)
+	su := p.getSu()
+
+	infoview := tview.NewTable()
+	infotitle := newTextView()
+
+	flex := tview.NewFlex().
+		AddItem(infotitle, 1, 0, false).
+		AddItem(infoview, 0, 1, true).
+		SetDirection(tview.FlexRow)
+
+	infotitle.SetText(getStyle("title").tag() + "Information (" + mode.String() + "): " + tview.Escape(path))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	infoview.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEscape, event.Key() == tcell.KeyEnter,
+			getKeyAction(kMain, event) == "info", getKeyAction(kMain, event) == "quit":
+			cancel()
+
+			pages.SwitchToPage("main")
+			pages.RemovePage("fileinfo")
+			app.SetFocus(p.table)
+
+			return nil
+		}
+
+		return event
+	})
+
+	infoview.SetCell(0, 0, tview.NewTableCell("Loading...").
+		SetTextColor(tcell.ColorDefault))
+
+	infoview.SetSelectable(true, false)
+	infoview.SetBackgroundColor(tcell.ColorDefault)
+	infoview.SetSelectedStyle(getStyle("cursor").style())
+
+	pages.AddAndSwitchToPage("fileinfo", flex, true)
+	app.SetFocus(infoview)
+
+	go func() {
+		var fields []fileInfoField
+		var err error
+
+		switch mode {
+		case mAdb:
+			fields, err = getAdbFileInfo(ctx, path, su)
+
+		case mLocal:
+			fields, err = getLocalFileInfo(ctx, path)
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		go app.QueueUpdateDraw(func() {
+			infoview.Clear()
+
+			if err != nil {
+				infoview.SetCell(0, 0, tview.NewTableCell(tview.Escape(err.Error())).
+					SetStyle(getStyle("error").style()))
+
+				return
+			}
+
+			for row, field := range fields {
+				infoview.SetCell(row, 0, tview.NewTableCell("[::b]"+field.name+" ").
+					SetTextColor(tcell.ColorDefault))
+
+				infoview.SetCell(row, 1, tview.NewTableCell(tview.Escape(field.value)).
+					SetExpansion(1).
+					SetStyle(getStyle("column").style()))
+			}
+
+			infoview.Select(0, 0)
+		})
+	}()
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// statTimes returns the access and status change times of a local file.
+func statTimes(info os.FileInfo) (time.Time, time.Time, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+
+	return time.Unix(st.Atim.Unix()), time.Unix(st.Ctim.Unix()), true
+}
+
+// getSELinuxContext returns the SELinux context of a local file, if any.
+func getSELinuxContext(path string) string {
+	buf := make([]byte, 256)
+
+	n, err := syscall.Getxattr(path, "security.selinux", buf)
+	if err != nil || n <= 0 {
+		return ""
+	}
+
+	return strings.TrimRight(string(buf[:n]), "\x00")
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// statTimes returns the access and status change times of a local file.
+func statTimes(info os.FileInfo) (time.Time, time.Time, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+
+	return time.Unix(st.Atimespec.Unix()), time.Unix(st.Ctimespec.Unix()), true
+}
+
+// getSELinuxContext returns the SELinux context of a local file, if any.
+func getSELinuxContext(path string) string {
+	return ""
+}
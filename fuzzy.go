@@ -0,0 +1,450 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/darkhz/tview"
+	"github.com/gdamore/tcell/v2"
+	adb "github.com/zach-klippenstein/goadb"
+)
+
+type fuzzyIndex struct {
+	mode   ifaceMode
+	root   string
+	depth  int
+	hidden bool
+	su     bool
+
+	files []string
+	done  bool
+	err   error
+	lock  sync.Mutex
+}
+
+type fuzzyMatch struct {
+	path  string
+	score int
+}
+
+const (
+	defaultFuzzyDepth = 8
+	maxFuzzyFiles     = 100000
+	maxFuzzyShown     = 100
+)
+
+func getFuzzyDepth() int {
+	if config.FuzzyDepth > 0 {
+		return config.FuzzyDepth
+	}
+
+	return defaultFuzzyDepth
+}
+
+// build indexes the entries under the root, relative to it.
+// Directories are recorded with a trailing slash.
+func (f *fuzzyIndex) build(ctx context.Context) {
+	var err error
+
+	switch f.mode {
+	case mAdb:
+		err = f.adbIndex(ctx)
+
+	case mLocal:
+		err = f.localIndex(ctx)
+	}
+
+	f.lock.Lock()
+	f.err = err
+	f.done = true
+	f.lock.Unlock()
+}
+
+func (f *fuzzyIndex) adbIndex(ctx context.Context) error {
+	find := fmt.Sprintf("find %s -mindepth 1 -maxdepth %d", shellQuote(findPath(f.root)), f.depth)
+	cmdtext := fmt.Sprintf("%s -type d 2>/dev/null | sed 's|$|/|'; %s ! -type d 2>/dev/null", find, find)
+	cmdtext = wrapAdbCommand(f.root, cmdtext, f.su)
+
+	logIndex := startLog(fmt.Sprintf("shell %s", cmdtext))
+
+	// find exits with an error status for unreadable directories,
+	// which are common on the device, so its output is still used.
+	out, err := exec.CommandContext(ctx, "adb", "shell", cmdtext).Output()
+	if err != nil && len(out) == 0 && ctx.Err() == nil {
+		updateLog(logIndex, err.Error(), true)
+		return err
+	}
+
+	var files []string
+
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		rel, err := filepath.Rel(f.root, line)
+		if line == "" || err != nil || !f.visible(rel) {
+			continue
+		}
+
+		if strings.HasSuffix(line, "/") {
+			rel += "/"
+		}
+
+		files = append(files, rel)
+		if len(files) >= maxFuzzyFiles {
+			break
+		}
+	}
+
+	status := fmt.Sprintf("%d entries", len(files))
+	if err != nil {
+		status += " (" + err.Error() + ")"
+	}
+
+	updateLog(logIndex, status, false)
+
+	f.lock.Lock()
+	f.files = files
+	f.lock.Unlock()
+
+	return nil
+}
+
+func (f *fuzzyIndex) localIndex(ctx context.Context) error {
+	var files []string
+
+	err := filepath.WalkDir(f.root, func(path string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil || len(files) >= maxFuzzyFiles {
+			return filepath.SkipAll
+		}
+
+		if err != nil || path == f.root {
+			return nil
+		}
+
+		rel, err := filepath.Rel(f.root, path)
+		if err != nil {
+			return nil
+		}
+
+		if !f.visible(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if d.IsDir() {
+			files = append(files, rel+"/")
+
+			if strings.Count(rel, "/")+1 >= f.depth {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		files = append(files, rel)
+
+		return nil
+	})
+
+	f.lock.Lock()
+	f.files = files
+	f.lock.Unlock()
+
+	return err
+}
+
+func (f *fuzzyIndex) visible(rel string) bool {
+	if !f.hidden {
+		return true
+	}
+
+	for _, name := range strings.Split(filepath.ToSlash(rel), "/") {
+		if strings.HasPrefix(name, ".") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// match returns the indexed entries matching pattern, best match first.
+func (f *fuzzyIndex) match(pattern string) []fuzzyMatch {
+	var matches []fuzzyMatch
+
+	f.lock.Lock()
+	files := f.files
+	f.lock.Unlock()
+
+	pattern = strings.ToLower(strings.ReplaceAll(pattern, " ", ""))
+
+	for _, file := range files {
+		score, ok := fuzzyScore(pattern, file)
+		if ok {
+			matches = append(matches, fuzzyMatch{file, score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+
+		return len(matches[i].path) < len(matches[j].path)
+	})
+
+	return matches
+}
+
+// fuzzyScore checks whether the characters of pattern appear in order
+// within str, and scores the match. Consecutive characters, characters
+// at the start of a word and characters in the file name score higher.
+// Every occurrence of the first pattern character is tried as a start.
+func fuzzyScore(pattern, str string) (int, bool) {
+	var best int
+	var found bool
+
+	if pattern == "" {
+		return 0, true
+	}
+
+	lower := strings.ToLower(str)
+	first, _ := utf8.DecodeRuneInString(pattern)
+
+	for i, r := range lower {
+		if r != first {
+			continue
+		}
+
+		if score, ok := fuzzyScoreFrom(pattern, lower, i); ok && (!found || score > best) {
+			best, found = score, true
+		}
+	}
+
+	return best, found
+}
+
+func fuzzyScoreFrom(pattern, lower string, start int) (int, bool) {
+	var score, pi int
+
+	base := strings.LastIndex(strings.TrimSuffix(lower, "/"), "/") + 1
+
+	prev := -1
+	prevRune := '/'
+
+	if start > 0 {
+		prevRune, _ = utf8.DecodeLastRuneInString(lower[:start])
+	}
+
+	for i, r := range lower[start:] {
+		i += start
+
+		pr, size := utf8.DecodeRuneInString(pattern[pi:])
+
+		if r == pr {
+			score++
+
+			switch {
+			case prev >= 0 && i == prev+utf8.RuneLen(prevRune):
+				score += 6
+
+			case prev >= 0:
+				score--
+			}
+
+			if i == 0 || strings.ContainsRune("/_-. ", prevRune) {
+				score += 8
+			}
+
+			if i >= base {
+				score += 2
+			}
+
+			prev = i
+			pi += size
+
+			if pi == len(pattern) {
+				return score, true
+			}
+		}
+
+		prevRune = r
+	}
+
+	return 0, false
+}
+
+func (p *dirPane) showFuzzyFinder() {
+	if p.mode == mAdb && !checkAdb() {
+		return
+	}
+
+	var matches []fuzzyMatch
+
+	index := &fuzzyIndex{
+		mode:   p.mode,
+		root:   filepath.Clean(p.getPath()),
+		depth:  getFuzzyDepth(),
+		hidden: p.getHidden(),
+		su:     p.getSu(),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	input := getStatusInput("Find (indexing):", false)
+
+	fztable := tview.NewTable()
+
+	flex := tview.NewFlex().
+		AddItem(fztable, 0, 10, false).
+		SetDirection(tview.FlexRow)
+
+	exit := func() {
+		cancel()
+
+		popupStatus(false)
+		pages.SwitchToPage("main")
+		statuspgs.SwitchToPage("statusmsg")
+		app.SetFocus(p.table)
+	}
+
+	reload := func(text string) {
+		matches = index.match(text)
+
+		index.lock.Lock()
+		done, count, err := index.done, len(index.files), index.err
+		index.lock.Unlock()
+
+		switch {
+		case err != nil:
+			input.SetLabel("[::b]Find (" + tview.Escape(err.Error()) + "): ")
+
+		case !done:
+			input.SetLabel("[::b]Find (indexing): ")
+
+		default:
+			input.SetLabel(fmt.Sprintf("[::b]Find (%d/%d): ", len(matches), count))
+		}
+
+		fztable.Clear()
+
+		for row, m := range matches {
+			if row >= maxFuzzyShown {
+				break
+			}
+
+			entry := &adb.DirEntry{Name: filepath.Base(m.path)}
+			if strings.HasSuffix(m.path, "/") {
+				entry.Mode = fs.ModeDir
+			}
+
+			style := setEntryColor(0, checkmsel(filepath.Join(index.root, m.path)), getEntryPerms(entry), entry)
+
+			cell := tview.NewTableCell(tview.Escape(m.path))
+			cell.SetReference(m)
+			fztable.SetCell(row, 0, cell.SetStyle(style.style()))
+		}
+
+		if fztable.GetRowCount() == 0 {
+			pages.HidePage("fuzzymodal")
+		} else {
+			if pg, _ := pages.GetFrontPage(); pg != "fuzzymodal" {
+				pages.SwitchToPage("fuzzymodal").ShowPage("main")
+			}
+
+			resizemodal()
+		}
+
+		app.SetFocus(input)
+
+		fztable.Select(0, 0)
+		fztable.ScrollToBeginning()
+	}
+
+	input.SetChangedFunc(func(text string) {
+		reload(text)
+	})
+
+	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch getKeyAction(kFuzzy, event) {
+		case "fuzzy-select":
+			row, _ := fztable.GetSelection()
+
+			m, ok := fztable.GetCell(row, 0).GetReference().(fuzzyMatch)
+			exit()
+
+			if ok {
+				path := filepath.Join(index.root, m.path)
+				p.jumpToEntry(index.mode, filepath.Dir(path), filepath.Base(path))
+			}
+
+			return nil
+
+		case "fuzzy-select-all":
+			if len(matches) == 0 || input.GetText() == "" {
+				return nil
+			}
+
+			for _, m := range matches {
+				addmsel(filepath.Join(index.root, m.path), index.mode)
+			}
+
+			selected = true
+
+			exit()
+
+			t := tabs[tabPos]
+			t.selPane.reselect(false)
+			t.auxPane.reselect(false)
+
+			showInfoMsg("Selected " + strconv.Itoa(len(matches)) + " matches")
+
+			return nil
+
+		case "fuzzy-exit":
+			exit()
+			return nil
+		}
+
+		switch event.Key() {
+		case tcell.KeyDown, tcell.KeyUp, tcell.KeyPgDn, tcell.KeyPgUp:
+			fztable.InputHandler()(event, nil)
+			return nil
+		}
+
+		return event
+	})
+
+	fztable.SetSelectedStyle(getStyle("cursor").style())
+
+	fztable.SetSelectable(true, false)
+	fztable.SetBackgroundColor(tcell.ColorDefault)
+
+	pages.AddAndSwitchToPage("fuzzymodal", statusmodal(flex, fztable), true).ShowPage("main")
+	pages.HidePage("fuzzymodal")
+
+	statuspgs.AddAndSwitchToPage("fuzzy", input, true)
+	app.SetFocus(input)
+
+	go func() {
+		index.build(ctx)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		go app.QueueUpdateDraw(func() {
+			reload(input.GetText())
+		})
+	}()
+}
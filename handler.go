@@ -2,11 +2,9 @@ package main
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"sync"
 
-	"github.com/fsnotify/fsnotify"
 	adb "github.com/zach-klippenstein/goadb"
 )
 
@@ -17,9 +15,7 @@ type selection struct {
 
 var (
 	selected       bool
-	openLock       sync.Mutex
 	selectLock     sync.RWMutex
-	openFiles      map[string]struct{}
 	multiselection map[string]ifaceMode
 )
 
@@ -92,22 +88,38 @@ func (p *dirPane) modeSwitchHandler() {
 	}
 	defer p.setUnlock()
 
+	p.saveViewState()
+
 	switch p.mode {
 	case mAdb:
-		p.mode = mLocal
-		p.apath = p.path
-		p.path = p.dpath
+		p.setMode(mLocal)
 
 	case mLocal:
 		if !checkAdb() {
 			return
 		}
-		p.mode = mAdb
+		p.setMode(mAdb)
+	}
+
+	p.ChangeDir(false, false)
+}
+
+func (p *dirPane) setMode(mode ifaceMode) {
+	if p.mode == mode {
+		return
+	}
+
+	switch mode {
+	case mLocal:
+		p.apath = p.path
+		p.path = p.dpath
+
+	case mAdb:
 		p.dpath = p.path
 		p.path = p.apath
 	}
 
-	p.ChangeDir(false, false)
+	p.mode = mode
 }
 
 func (p *dirPane) multiSelectHandler(all, inverse bool, totalrows int) {
@@ -168,127 +180,106 @@ func (p *dirPane) multiSelectHandler(all, inverse bool, totalrows int) {
 	p.table.Select(pos, 0)
 }
 
-func (p *dirPane) openFileHandler() {
-	p.updateRef(true)
-
-	if p.entry.Mode.IsDir() {
+// openFileHandler copies the entry to the staging directory and opens it
+// with op, or with the first opener matching it if op is nil. The copy
+// is tracked, so that changes made to it are copied back to the entry.
+func (p *dirPane) openFileHandler(entry *adb.DirEntry, op *fileOpener) {
+	if entry == nil || entry.Mode.IsDir() {
 		return
 	}
 
-	name := p.entry.Name
-	tpath := filepath.Join("/tmp", name)
+	name := entry.Name
 	fpath := filepath.Join(p.getPath(), name)
 
+	if of := getOpenedFile(p.mode, fpath); of != nil {
+		p.openTrackedFile(of, op)
+		return
+	}
+
+	tpath, err := getStagingPath(p.mode, fpath)
+	if err != nil {
+		showErrorMsg(fmt.Errorf("Unable to open '%s': %s", name, err.Error()), false)
+		return
+	}
+
 	showInfoMsg(fmt.Sprintf("Transferring '%s', check operations view", name))
 
 	tmpdst, err := startOperation(
 		p,
 		&dirPane{path: tpath, mode: mLocal},
 		opCopy,
-		false,
+		true,
 		[]selection{{fpath, p.mode}},
 	)
 	if err != nil {
+		removeStaged(tpath)
 		showErrorMsg(
 			fmt.Errorf("Unable to open '%s': %s", name, err.Error()),
 			false,
 		)
 		return
 	}
-	defer os.Remove(tmpdst)
-
-	if checkOpen(fpath) {
-		showInfoMsg(fmt.Sprintf("Waiting for process to finish with '%s'", name))
-		return
-	}
-	setOpen(fpath, false)
-	defer setOpen(fpath, true)
-
-	showInfoMsg("Opening " + name)
 
-	watcher, err := fsnotify.NewWatcher()
+	of, err := trackFile(p, p.mode, fpath, tmpdst)
 	if err != nil {
-		showErrorMsg(fmt.Errorf("Cannot monitor changes on '%s'", name), false)
+		removeStaged(tmpdst)
+		showErrorMsg(fmt.Errorf("Cannot monitor changes on '%s': %s", name, err.Error()), false)
 		return
 	}
 
-	err = watcher.Add(tmpdst)
-	if err != nil {
-		showErrorMsg(fmt.Errorf("Error monitoring '%s'", name), false)
-		return
-	}
-	defer watcher.Close()
-
-	modify := make(chan bool)
-	defer close(modify)
-
-	go func() {
-		select {
-		case _, ok := <-watcher.Events:
-			if !ok {
-				return
-			}
+	p.openTrackedFile(of, op)
+}
 
-			modify <- true
-		}
-	}()
+// openTrackedFile opens the local copy of a tracked file. Once a waited
+// opener exits, pending changes are copied back without delay.
+func (p *dirPane) openTrackedFile(of *openedFile, op *fileOpener) {
+	name := filepath.Base(of.path)
 
-	cmd, err := execCmd(fmt.Sprintf("xdg-open '%s'", tmpdst), "Background", "Local")
-	if err != nil {
-		showErrorMsg(err, false)
-		return
+	if op == nil {
+		o := findOpener(of.local)
+		op = &o
 	}
 
-	err = cmd.Wait()
-	if err != nil {
-		showErrorMsg(err, false)
+	of.lock.Lock()
+	if of.running > 0 && of.opener == op.Name {
+		of.lock.Unlock()
+		showInfoMsg(fmt.Sprintf("'%s' is already open with %s", name, op.Name))
 		return
 	}
 
-	select {
-	case <-modify:
-		_, err = startOperation(
-			p,
-			&dirPane{path: fpath, mode: p.mode},
-			opCopy,
-			true,
-			[]selection{{tmpdst, mLocal}},
-		)
+	of.opener = op.Name
+	of.detached = false
+	of.running++
+	of.lock.Unlock()
 
-		if err != nil {
-			showErrorMsg(
-				fmt.Errorf("Unable to save '%s': %s", name, err.Error()),
-				false,
-			)
+	refreshOpenedFiles()
+	showInfoMsg(fmt.Sprintf("Opening %s with %s", name, op.Name))
 
-			return
-		}
+	exited, err := op.run(of.local)
 
-		showInfoMsg(fmt.Sprintf("Overwriting modified '%s'", name))
+	of.lock.Lock()
+	of.running--
+	of.detached = !exited && err == nil
 
-	default:
+	pending := of.timer != nil
+	if pending {
+		of.timer.Stop()
 	}
-}
-
-func checkOpen(fpath string) bool {
-	openLock.Lock()
-	defer openLock.Unlock()
+	of.lock.Unlock()
 
-	_, ok := openFiles[fpath]
+	refreshOpenedFiles()
 
-	return ok
-}
+	if err != nil {
+		showErrorMsg(fmt.Errorf("%s: %s", op.Name, err.Error()), false)
+	}
 
-func setOpen(fpath string, del bool) {
-	openLock.Lock()
-	defer openLock.Unlock()
+	switch {
+	case pending:
+		of.sync(false)
 
-	if del {
-		delete(openFiles, fpath)
-		return
+	case !exited && err == nil:
+		showInfoMsg(fmt.Sprintf("Opened '%s' with %s, changes are copied back while it is tracked", name, op.Name))
 	}
-
-	openFiles[fpath] = struct{}{}
 }
 
 func checkSelected(panepath, dirname string, rm bool) bool {
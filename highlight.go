@@ -0,0 +1,188 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/darkhz/tview"
+)
+
+type syntax struct {
+	comment string
+	block   [2]string
+	markup  bool
+}
+
+var (
+	cSyntax     = syntax{comment: "//", block: [2]string{"/*", "*/"}}
+	shellSyntax = syntax{comment: "#"}
+	sqlSyntax   = syntax{comment: "--", block: [2]string{"/*", "*/"}}
+	iniSyntax   = syntax{comment: ";"}
+	xmlSyntax   = syntax{block: [2]string{"<!--", "-->"}, markup: true}
+)
+
+var syntaxByExt = map[string]syntax{
+	".c": cSyntax, ".h": cSyntax, ".cc": cSyntax, ".cpp": cSyntax, ".hpp": cSyntax,
+	".cs": cSyntax, ".go": cSyntax, ".java": cSyntax, ".js": cSyntax, ".ts": cSyntax,
+	".kt": cSyntax, ".kts": cSyntax, ".rs": cSyntax, ".swift": cSyntax, ".dart": cSyntax,
+	".scala": cSyntax, ".gradle": cSyntax, ".css": cSyntax, ".php": cSyntax, ".json": cSyntax,
+	".aidl": cSyntax,
+
+	".sh": shellSyntax, ".bash": shellSyntax, ".zsh": shellSyntax, ".rc": shellSyntax,
+	".py": shellSyntax, ".rb": shellSyntax, ".pl": shellSyntax, ".yaml": shellSyntax,
+	".yml": shellSyntax, ".toml": shellSyntax, ".conf": shellSyntax, ".prop": shellSyntax,
+	".properties": shellSyntax, ".cfg": shellSyntax, ".mk": shellSyntax,
+
+	".sql": sqlSyntax, ".lua": sqlSyntax,
+
+	".ini": iniSyntax,
+
+	".xml": xmlSyntax, ".html": xmlSyntax, ".htm": xmlSyntax, ".svg": xmlSyntax,
+}
+
+var syntaxKeywords = map[string]bool{}
+
+func init() {
+	for _, k := range strings.Fields(`
+		if else elif for while do done then fi case esac switch default break continue
+		return func function def fn class struct interface enum type import package from
+		as in is not and or const var let val static public private protected final
+		void new delete try catch except finally throw throws raise with yield go defer
+		true false null nil None True False this self super extends implements override
+		export local echo select insert update where values create table
+	`) {
+		syntaxKeywords[k] = true
+	}
+}
+
+// highlightText colours the comments, strings, numbers and keywords
+// of text, using a rough syntax chosen by the extension of name.
+// Files of unknown types are returned without colours.
+func highlightText(name string, text string) string {
+	syn, ok := syntaxByExt[strings.ToLower(filepath.Ext(name))]
+	if !ok {
+		return tview.Escape(text)
+	}
+
+	var out, plain strings.Builder
+	var inBlock bool
+
+	emit := func(style, token string) {
+		out.WriteString(tview.Escape(plain.String()))
+		plain.Reset()
+
+		out.WriteString(getStyle(style).tag() + tview.Escape(token) + "[-:-:-]")
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		for line != "" {
+			if inBlock {
+				end := strings.Index(line, syn.block[1])
+				if end < 0 {
+					emit("comment", strings.TrimSuffix(line, "\n"))
+					plain.WriteString(line[len(strings.TrimSuffix(line, "\n")):])
+					break
+				}
+
+				end += len(syn.block[1])
+				emit("comment", line[:end])
+				line = line[end:]
+				inBlock = false
+
+				continue
+			}
+
+			switch {
+			case syn.block[0] != "" && strings.HasPrefix(line, syn.block[0]):
+				inBlock = true
+				continue
+
+			case syn.comment != "" && strings.HasPrefix(line, syn.comment):
+				comment := strings.TrimSuffix(line, "\n")
+				emit("comment", comment)
+				line = line[len(comment):]
+
+				continue
+
+			case syn.markup && line[0] == '<':
+				end := strings.IndexAny(line, " >\n")
+				if end < 0 {
+					end = len(line)
+				} else if line[end] == '>' {
+					end++
+				}
+
+				emit("keyword", line[:end])
+				line = line[end:]
+
+				continue
+
+			case line[0] == '"' || line[0] == '\'' || line[0] == '`':
+				end := stringEnd(line)
+				emit("string", line[:end])
+				line = line[end:]
+
+				continue
+			}
+
+			r := rune(line[0])
+
+			if isWordChar(r) {
+				end := 1
+				for end < len(line) && isWordChar(rune(line[end])) {
+					end++
+				}
+
+				word := line[:end]
+
+				switch {
+				case unicode.IsDigit(r):
+					emit("number", word)
+
+				case syntaxKeywords[word] && !syn.markup:
+					emit("keyword", word)
+
+				default:
+					plain.WriteString(word)
+				}
+
+				line = line[end:]
+
+				continue
+			}
+
+			plain.WriteByte(line[0])
+			line = line[1:]
+		}
+	}
+
+	out.WriteString(tview.Escape(plain.String()))
+
+	return out.String()
+}
+
+// stringEnd returns the end of the quoted string at the start of line,
+// or the end of the line if the string is not terminated on it.
+func stringEnd(line string) int {
+	quote := line[0]
+
+	for i := 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+
+		case quote:
+			return i + 1
+
+		case '\n':
+			return i
+		}
+	}
+
+	return len(line)
+}
+
+func isWordChar(r rune) bool {
+	return r == '_' || r >= 0x80 || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,440 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/darkhz/tview"
+	"github.com/gdamore/tcell/v2"
+)
+
+// installUnit is a set of APKs installed in one step: a single APK,
+// the splits of one app, or the contents of an .apks/.apkm archive.
+type installUnit struct {
+	name    string
+	paths   []string
+	archive bool
+}
+
+var apkAbis = []string{"arm64_v8a", "armeabi_v7a", "armeabi", "x86_64", "x86"}
+
+func isApkFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".apk", ".apks", ".apkm":
+		return true
+	}
+
+	return false
+}
+
+// getInstallUnits groups the files to install. Each APK is installed
+// on its own, except for a base.apk and the split_*.apk and config.*.apk
+// splits next to it, which are installed together as one app. Each
+// archive is installed on its own.
+func getInstallUnits(paths []string) []installUnit {
+	var units []installUnit
+
+	bases := make(map[string]bool)
+	for _, path := range paths {
+		if strings.ToLower(filepath.Base(path)) == "base.apk" {
+			bases[filepath.Dir(path)] = true
+		}
+	}
+
+	apps := make(map[string]int)
+
+	for _, path := range paths {
+		name := filepath.Base(path)
+
+		if strings.ToLower(filepath.Ext(name)) != ".apk" {
+			units = append(units, installUnit{
+				name:    name,
+				paths:   []string{path},
+				archive: true,
+			})
+
+			continue
+		}
+
+		if dir := filepath.Dir(path); bases[dir] && isAppApk(name) {
+			if i, ok := apps[dir]; ok {
+				units[i].paths = append(units[i].paths, path)
+				units[i].name = fmt.Sprintf(
+					"%s and %d more",
+					filepath.Join(filepath.Base(dir), "base.apk"), len(units[i].paths)-1,
+				)
+
+				continue
+			}
+
+			apps[dir] = len(units)
+		}
+
+		units = append(units, installUnit{name: name, paths: []string{path}})
+	}
+
+	return units
+}
+
+// isAppApk reports whether name is the base or a split APK of an app,
+// as stored in its installation directory.
+func isAppApk(name string) bool {
+	name = strings.ToLower(name)
+
+	return name == "base.apk" || strings.HasPrefix(name, "split_") || strings.HasPrefix(name, "config.")
+}
+
+// extractApks extracts the APKs of an .apks or .apkm archive to dir.
+// A universal APK is preferred if present; otherwise the splits are
+// used, skipping those for ABIs which are not in abis.
+func extractApks(archive, dir string, abis []string) ([]string, error) {
+	var paths []string
+	var files, universal []*zip.File
+
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		name := strings.ToLower(f.Name)
+		if !strings.HasSuffix(name, ".apk") || strings.HasPrefix(name, "standalones/") {
+			continue
+		}
+
+		if filepath.Base(name) == "universal.apk" {
+			universal = append(universal, f)
+			continue
+		}
+
+		if abi := getSplitAbi(name); abi != "" && len(abis) > 0 && !containsString(abis, abi) {
+			continue
+		}
+
+		files = append(files, f)
+	}
+
+	if len(universal) > 0 {
+		files = universal
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("'%s' contains no APKs", filepath.Base(archive))
+	}
+
+	for _, f := range files {
+		path := filepath.Join(dir, strings.ReplaceAll(filepath.Clean("/" + f.Name)[1:], "/", "_"))
+
+		if err := extractZipFile(f, path); err != nil {
+			return nil, err
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+// getSplitAbi returns the ABI a split APK is built for, if any.
+func getSplitAbi(name string) string {
+	name = strings.ReplaceAll(strings.ToLower(filepath.Base(name)), "-", "_")
+
+	for _, abi := range apkAbis {
+		if strings.Contains(name, abi) {
+			return strings.ReplaceAll(abi, "_", "-")
+		}
+	}
+
+	return ""
+}
+
+func containsString(list []string, str string) bool {
+	for _, s := range list {
+		if s == str {
+			return true
+		}
+	}
+
+	return false
+}
+
+func extractZipFile(f *zip.File, path string) error {
+	rd, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, rd)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// getDeviceAbis returns the ABIs supported by the device.
+func getDeviceAbis() []string {
+	device, err := getAdb()
+	if err != nil {
+		return nil
+	}
+
+	out, err := runAdbShellCommand(device, "getprop ro.product.cpu.abilist")
+	if err != nil {
+		return nil
+	}
+
+	var abis []string
+
+	for _, abi := range strings.Split(strings.TrimSpace(out), ",") {
+		if abi = strings.TrimSpace(abi); abi != "" {
+			abis = append(abis, abi)
+		}
+	}
+
+	return abis
+}
+
+// install installs one unit with "adb install" or "adb install-multiple".
+// The output of the package manager is shown as the progress description.
+func (o *operation) install(unit installUnit, flags []string, abis []string) error {
+	paths := unit.paths
+
+	if unit.archive {
+		dir, err := os.MkdirTemp(getStagingRoot(), "adbtuifm-install-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+
+		o.progress.pbar.Describe("Extracting")
+
+		paths, err = extractApks(paths[0], dir, abis)
+		if err != nil {
+			return err
+		}
+	}
+
+	cmdname := "install"
+	if len(paths) > 1 {
+		cmdname = "install-multiple"
+	}
+
+	var args []string
+
+	if serial := getDeviceSerial(); serial != "" {
+		args = append(args, "-s", serial)
+	}
+
+	args = append(args, cmdname)
+	args = append(args, flags...)
+	args = append(args, paths...)
+
+	logIndex := startLog(strings.Join(args, " "))
+
+	cmd := exec.CommandContext(o.ctx, "adb", args...)
+
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+
+	if err := cmd.Start(); err != nil {
+		updateLog(logIndex, err.Error(), true)
+		return err
+	}
+
+	go func() {
+		pw.CloseWithError(cmd.Wait())
+	}()
+
+	var output []string
+	var result string
+
+	scanner := bufio.NewScanner(pr)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		output = append(output, line)
+
+		if strings.HasPrefix(line, "Success") || strings.HasPrefix(line, "Failure") ||
+			strings.HasPrefix(line, "adb: failed") || strings.HasPrefix(line, "adb: error") {
+			result = line
+		}
+
+		o.progress.pbar.Describe(tview.Escape(line))
+	}
+
+	err := scanner.Err()
+	if o.ctx.Err() != nil {
+		err = context.Canceled
+	}
+
+	updateLog(logIndex, strings.Join(output, "\n"), err != nil || !strings.HasPrefix(result, "Success"))
+
+	switch {
+	case err == context.Canceled:
+		return err
+
+	case strings.HasPrefix(result, "Success"):
+		return nil
+
+	case result != "":
+		return errors.New(result)
+
+	case err != nil:
+		if len(output) > 0 {
+			return errors.New(output[len(output)-1])
+		}
+
+		return err
+	}
+
+	return errors.New("no result from the package manager")
+}
+
+// startInstall installs the APKs at paths on the device, as
+// one operation with an entry per installed unit.
+func startInstall(srcPane *dirPane, paths []string, flags []string) {
+	var err error
+	var abis []string
+
+	units := getInstallUnits(paths)
+
+	for _, unit := range units {
+		if unit.archive {
+			abis = getDeviceAbis()
+			break
+		}
+	}
+
+	op := newOperation(opInstall, srcPane.getSortMethod())
+
+	op.opSetStatus(opInProgress, nil)
+
+	for i, unit := range units {
+		if err = op.setNewProgress(unit.name, "", i, len(units)); err != nil {
+			break
+		}
+
+		if err = op.install(unit, flags, abis); err != nil {
+			if err != context.Canceled {
+				err = fmt.Errorf("Unable to install '%s': %s", unit.name, err.Error())
+			}
+
+			break
+		}
+
+		showInfoMsg(fmt.Sprintf("Installed '%s'", unit.name))
+	}
+
+	op.opSetStatus(opDone, err)
+}
+
+// installHandler asks for the install options, and installs the
+// selected APKs, or the highlighted one if none are selected.
+func (p *dirPane) installHandler(auxPane *dirPane) {
+	var paths []string
+
+	if p.mode != mLocal {
+		showInfoMsg("APKs can only be installed from a local pane")
+		return
+	}
+
+	for _, sel := range getselection() {
+		if sel.smode == mLocal && isApkFile(sel.path) {
+			paths = append(paths, sel.path)
+		}
+	}
+
+	if len(paths) == 0 {
+		p.updateRef(false)
+
+		if p.entry != nil && !p.entry.Mode.IsDir() && isApkFile(p.entry.Name) {
+			paths = append(paths, filepath.Join(p.getPath(), p.entry.Name))
+		}
+	}
+
+	if len(paths) == 0 {
+		showInfoMsg("No APKs selected or highlighted")
+		return
+	}
+
+	if !checkAdb() {
+		return
+	}
+
+	input := getStatusInput(
+		fmt.Sprintf("Install %d file(s) with options (r: reinstall, d: downgrade, g: grant permissions):", len(paths)),
+		false,
+	)
+	input.SetText("r")
+
+	exit := func() {
+		statuspgs.SwitchToPage("statusmsg")
+		app.SetFocus(p.table)
+	}
+
+	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEnter:
+			var flags []string
+
+			for _, opt := range input.GetText() {
+				switch opt {
+				case 'r':
+					flags = append(flags, "-r")
+
+				case 'd':
+					flags = append(flags, "-d")
+
+				case 'g':
+					flags = append(flags, "-g")
+
+				case ' ':
+
+				default:
+					showErrorMsg(fmt.Errorf("Unknown install option '%c'", opt), false)
+					return nil
+				}
+			}
+
+			exit()
+
+			if selected {
+				reset(p, auxPane)
+			}
+
+			showInfoMsg(fmt.Sprintf("Installing %d file(s), check operations view", len(paths)))
+			go startInstall(p, paths, flags)
+
+			return nil
+
+		case tcell.KeyEscape:
+			exit()
+			return nil
+		}
+
+		return event
+	})
+
+	statuspgs.AddAndSwitchToPage("install", input, true)
+	app.SetFocus(input)
+}
@@ -0,0 +1,446 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+type keyContext int
+
+const (
+	kMain keyContext = iota
+	kOps
+	kChangeDir
+	kEditSel
+	kExec
+	kFilter
+	kLog
+	kBookmarks
+	kSearch
+	kFuzzy
+	kDiskUsage
+	kVolumes
+	kPager
+	kEditor
+	kOpenWith
+	kOpened
+	kApps
+	kGlobal
+)
+
+func (k keyContext) String() string {
+	ctxstr := [...]string{
+		"MAIN PAGE",
+		"OPERATIONS PAGE",
+		"CHANGE DIRECTORY MODE",
+		"EDIT SELECTION MODE",
+		"EXECUTION MODE",
+		"FILTER MODE",
+		"LOG VIEW",
+		"BOOKMARKS",
+		"SEARCH RESULTS",
+		"FUZZY FINDER",
+		"DISK USAGE",
+		"VOLUMES",
+		"PAGER",
+		"EDITOR",
+		"OPEN WITH",
+		"OPENED FILES",
+		"APPS",
+		"GLOBAL",
+	}
+
+	return ctxstr[k]
+}
+
+type keyBinding struct {
+	key tcell.Key
+	ch  rune
+	mod tcell.ModMask
+}
+
+type keyAction struct {
+	name  string
+	desc  string
+	fixed bool
+	ctx   keyContext
+	keys  []keyBinding
+}
+
+var keyActions []*keyAction
+
+var defaultKeys = []struct {
+	ctx   keyContext
+	name  string
+	desc  string
+	keys  []string
+	fixed bool
+}{
+	{kMain, "switch-pane", "Switch between panes", []string{"Tab", "Backtab"}, false},
+	{kMain, "navigate", "Navigate between entries", []string{"Up", "Down"}, true},
+	{kMain, "cd-entry", "CD highlighted entry", []string{"Enter", "Right"}, false},
+	{kMain, "cd-back", "Change one directory back", []string{"Backspace", "Left"}, false},
+	{kMain, "ops-page", "Switch to operations page", []string{"o"}, false},
+	{kMain, "log", "View fullscreen log", []string{"l"}, false},
+	{kMain, "switch-mode", "Switch between ADB/Local", []string{"s", "<"}, false},
+	{kMain, "change-dir", "Change to any directory", []string{"g", ">"}, false},
+	{kMain, "toggle-hidden", "Toggle hidden files", []string{"h", "."}, false},
+	{kMain, "exec", "Execute command", []string{"!"}, false},
+	{kMain, "refresh", "Refresh", []string{"r"}, false},
+	{kMain, "move", "Move", []string{"m"}, false},
+	{kMain, "paste", "Paste/Put (duplicate existing)", []string{"p"}, false},
+	{kMain, "paste-overwrite", "Paste/Put (overwrite existing)", []string{"P"}, false},
+	{kMain, "delete", "Delete", []string{"d"}, false},
+	{kMain, "open", "Open files", []string{"Ctrl+o"}, false},
+	{kMain, "open-with", "Open files with a chosen program", []string{"O"}, false},
+	{kMain, "opened-files", "Show opened files", []string{"Ctrl+t"}, false},
+	{kMain, "install", "Install APKs on the device", []string{"I"}, false},
+	{kMain, "apps", "Show installed apps", []string{"Ctrl+p"}, false},
+	{kMain, "run-as", "Browse app data (run-as)", []string{"D"}, false},
+	{kMain, "root-mode", "Toggle root mode (su)", []string{"#"}, false},
+	{kMain, "mkdir", "Make directory", []string{"M"}, false},
+	{kMain, "rename", "Rename files/folders", []string{"R"}, false},
+	{kMain, "filter", "Filter entries", []string{"/"}, false},
+	{kMain, "sort", "Sort entries", []string{";"}, false},
+	{kMain, "clear-filter", "Clear filtered entries", []string{"Ctrl+r"}, false},
+	{kMain, "select-one", "Select one item", []string{"Space"}, false},
+	{kMain, "select-invert", "Invert selection", []string{"a"}, false},
+	{kMain, "select-all", "Select all items", []string{"A"}, false},
+	{kMain, "edit-selections", "Edit selection list", []string{"S"}, false},
+	{kMain, "history-back", "Navigate back in history", []string{"["}, false},
+	{kMain, "history-forward", "Navigate forward in history", []string{"]"}, false},
+	{kMain, "bookmark-add", "Bookmark current directory", []string{"b"}, false},
+	{kMain, "bookmarks", "Show bookmarks", []string{"B"}, false},
+	{kMain, "bookmark-jump", "Jump to bookmark 1-9", []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"}, false},
+	{kMain, "preview", "Toggle the preview pane", []string{"z"}, false},
+	{kMain, "view", "View file", []string{"E"}, false},
+	{kMain, "edit", "Edit file", []string{"e"}, false},
+	{kMain, "info", "Show file information", []string{"i"}, false},
+	{kMain, "volumes", "Show storage volumes", []string{"V"}, false},
+	{kMain, "volume-switch", "Switch to a storage volume", []string{"v"}, false},
+	{kMain, "disk-usage", "Show disk usage", []string{"u"}, false},
+	{kMain, "fuzzy", "Fuzzy find files", []string{"f"}, false},
+	{kMain, "search", "Search files recursively", []string{"F"}, false},
+	{kMain, "tab-new", "Open a new tab", []string{"t"}, false},
+	{kMain, "tab-close", "Close the current tab", []string{"w"}, false},
+	{kMain, "tab-rename", "Rename the current tab", []string{"T"}, false},
+	{kMain, "tab-next", "Switch to the next tab", []string{"}"}, false},
+	{kMain, "tab-prev", "Switch to the previous tab", []string{"{"}, false},
+	{kMain, "reset", "Reset selections", []string{"Esc"}, false},
+	{kMain, "help", "Help", []string{"?"}, false},
+	{kMain, "quit", "Quit", []string{"q"}, false},
+
+	{kOps, "ops-navigate", "Navigate between entries", []string{"Up", "Down"}, true},
+	{kOps, "ops-cancel", "Cancel selected operation", []string{"x"}, false},
+	{kOps, "ops-cancel-all", "Cancel all operations", []string{"X"}, false},
+	{kOps, "ops-exit", "Switch to main page", []string{"o", "Esc"}, false},
+
+	{kChangeDir, "cd-navigate", "Navigate between entries", []string{"Up", "Down"}, true},
+	{kChangeDir, "cd-complete", "Autocomplete", []string{"Tab"}, false},
+	{kChangeDir, "cd-select", "CD to highlighted entry", []string{"Enter"}, false},
+	{kChangeDir, "cd-up", "Move back a directory", []string{"Ctrl+w"}, false},
+	{kChangeDir, "cd-exit", "Switch to main page", []string{"Esc"}, false},
+
+	{kEditSel, "edit-select-one", "Select one item", []string{"Alt+Space"}, false},
+	{kEditSel, "edit-select-invert", "Invert selection", []string{"Alt+a"}, false},
+	{kEditSel, "edit-select-all", "Select all items", []string{"Alt+A"}, false},
+	{kEditSel, "edit-save", "Save edited list", []string{"Ctrl+s"}, false},
+	{kEditSel, "edit-cancel", "Cancel editing list", []string{"Esc"}, false},
+
+	{kExec, "exec-iface", "Switch b/w Local/Adb", []string{"Ctrl+a"}, false},
+	{kExec, "exec-mode", "Switch b/w FG/BG execution", []string{"Ctrl+q"}, false},
+
+	{kFilter, "filter-regex", "Toggle filtering modes (normal/regex)", []string{"Ctrl+f"}, false},
+	{kFilter, "filter-clear", "Clear filtered entries", []string{"Ctrl+r"}, false},
+
+	{kLog, "log-exit", "Switch to main page", []string{"Esc", "l", "q"}, false},
+	{kLog, "log-clear", "Clear log", []string{"c"}, false},
+
+	{kBookmarks, "bookmark-navigate", "Navigate between entries", []string{"Up", "Down"}, true},
+	{kBookmarks, "bookmark-select", "Go to highlighted bookmark", []string{"Enter"}, false},
+	{kBookmarks, "bookmark-delete", "Delete highlighted bookmark", []string{"Ctrl+x"}, false},
+	{kBookmarks, "bookmark-exit", "Switch to main page", []string{"Esc"}, false},
+
+	{kSearch, "search-navigate", "Navigate between results", []string{"Up", "Down"}, true},
+	{kSearch, "search-goto", "Go to the directory of the result", []string{"Enter"}, false},
+	{kSearch, "search-select", "Select one result", []string{"Space"}, false},
+	{kSearch, "search-select-all", "Select all results", []string{"A"}, false},
+	{kSearch, "search-cancel", "Stop searching", []string{"x"}, false},
+	{kSearch, "search-exit", "Switch to main page", []string{"Esc", "q"}, false},
+
+	{kFuzzy, "fuzzy-navigate", "Navigate between matches", []string{"Up", "Down"}, true},
+	{kFuzzy, "fuzzy-select", "Go to highlighted match", []string{"Enter"}, false},
+	{kFuzzy, "fuzzy-select-all", "Select all matches", []string{"Ctrl+a"}, false},
+	{kFuzzy, "fuzzy-exit", "Switch to main page", []string{"Esc"}, false},
+
+	{kDiskUsage, "du-navigate", "Navigate between entries", []string{"Up", "Down"}, true},
+	{kDiskUsage, "du-open", "Show usage of highlighted directory", []string{"Enter", "Right"}, false},
+	{kDiskUsage, "du-back", "Show usage of parent directory", []string{"Backspace", "Left"}, false},
+	{kDiskUsage, "du-select", "Select one item", []string{"Space"}, false},
+	{kDiskUsage, "du-delete", "Delete selected items", []string{"d"}, false},
+	{kDiskUsage, "du-refresh", "Recalculate", []string{"r"}, false},
+	{kDiskUsage, "du-exit", "Switch to main page", []string{"Esc", "q"}, false},
+
+	{kVolumes, "volume-navigate", "Navigate between volumes", []string{"Up", "Down"}, true},
+	{kVolumes, "volume-select", "Go to highlighted volume", []string{"Enter"}, false},
+	{kVolumes, "volume-refresh", "Refresh", []string{"r"}, false},
+	{kVolumes, "volume-exit", "Switch to main page", []string{"Esc", "q"}, false},
+
+	{kPager, "pager-navigate", "Scroll", []string{"Up", "Down", "PgUp", "PgDn"}, true},
+	{kPager, "pager-edit", "Edit file", []string{"e"}, false},
+	{kPager, "pager-exit", "Switch to main page", []string{"Esc", "q"}, false},
+
+	{kEditor, "editor-navigate", "Move the cursor", []string{"Up", "Down", "Left", "Right"}, true},
+	{kEditor, "editor-save", "Save file", []string{"Ctrl+s"}, false},
+	{kEditor, "editor-exit", "Switch to main page", []string{"Esc"}, false},
+
+	{kOpenWith, "openwith-navigate", "Navigate between programs", []string{"Up", "Down"}, true},
+	{kOpenWith, "openwith-select", "Open with highlighted program", []string{"Enter"}, false},
+	{kOpenWith, "openwith-exit", "Switch to main page", []string{"Esc"}, false},
+
+	{kOpened, "opened-navigate", "Navigate between files", []string{"Up", "Down"}, true},
+	{kOpened, "opened-open", "Open highlighted file again", []string{"Enter"}, false},
+	{kOpened, "opened-push", "Copy back, overwriting the source", []string{"p"}, false},
+	{kOpened, "opened-reload", "Reload from the source, discarding changes", []string{"r"}, false},
+	{kOpened, "opened-stop", "Stop tracking highlighted file", []string{"d"}, false},
+	{kOpened, "opened-exit", "Switch to main page", []string{"Esc", "q"}, false},
+
+	{kApps, "apps-navigate", "Navigate between apps", []string{"Up", "Down"}, true},
+	{kApps, "apps-data", "Browse app data (run-as)", []string{"Enter"}, false},
+	{kApps, "apps-copy", "Copy APKs to the local pane", []string{"p"}, false},
+	{kApps, "apps-uninstall", "Uninstall app", []string{"d"}, false},
+	{kApps, "apps-clear", "Clear app data", []string{"c"}, false},
+	{kApps, "apps-stop", "Force-stop app", []string{"x"}, false},
+	{kApps, "apps-type", "Show user/system/all apps", []string{"t"}, false},
+	{kApps, "apps-filter", "Filter apps", []string{"/"}, false},
+	{kApps, "apps-refresh", "Refresh", []string{"r"}, false},
+	{kApps, "apps-exit", "Switch to main page", []string{"Esc", "q"}, false},
+
+	{kGlobal, "local-shell", "Launch local shell", []string{"Ctrl+d"}, false},
+	{kGlobal, "adb-shell", "Launch ADB shell", []string{"Alt+d"}, false},
+	{kGlobal, "suspend", "Suspend to shell", []string{"Ctrl+z"}, false},
+}
+
+var namedKeys = map[string]tcell.Key{
+	"enter":     tcell.KeyEnter,
+	"tab":       tcell.KeyTab,
+	"backtab":   tcell.KeyBacktab,
+	"esc":       tcell.KeyEscape,
+	"escape":    tcell.KeyEscape,
+	"backspace": tcell.KeyBackspace2,
+	"delete":    tcell.KeyDelete,
+	"insert":    tcell.KeyInsert,
+	"up":        tcell.KeyUp,
+	"down":      tcell.KeyDown,
+	"left":      tcell.KeyLeft,
+	"right":     tcell.KeyRight,
+	"home":      tcell.KeyHome,
+	"end":       tcell.KeyEnd,
+	"pgup":      tcell.KeyPgUp,
+	"pgdn":      tcell.KeyPgDn,
+}
+
+func init() {
+	for _, k := range defaultKeys {
+		action := &keyAction{
+			name:  k.name,
+			desc:  k.desc,
+			ctx:   k.ctx,
+			fixed: k.fixed,
+		}
+
+		for _, key := range k.keys {
+			kb, err := parseKey(key)
+			if err != nil {
+				panic(err)
+			}
+
+			action.keys = append(action.keys, kb)
+		}
+
+		keyActions = append(keyActions, action)
+	}
+}
+
+func parseKey(key string) (keyBinding, error) {
+	var kb keyBinding
+
+	name := key
+
+	switch {
+	case strings.HasPrefix(strings.ToLower(name), "ctrl+") && len(name) > 5:
+		r, size := utf8.DecodeRuneInString(name[5:])
+		if size != len(name)-5 {
+			break
+		}
+
+		r = []rune(strings.ToLower(string(r)))[0]
+		if r < 'a' || r > 'z' {
+			return kb, fmt.Errorf("invalid key '%s'", key)
+		}
+
+		kb.key = tcell.KeyCtrlA + tcell.Key(r-'a')
+		kb.mod = tcell.ModCtrl
+
+		return kb, nil
+
+	case strings.HasPrefix(strings.ToLower(name), "alt+") && len(name) > 4:
+		kb.mod = tcell.ModAlt
+		name = name[4:]
+	}
+
+	if k, ok := namedKeys[strings.ToLower(name)]; ok && kb.mod == tcell.ModNone {
+		kb.key = k
+		return kb, nil
+	}
+
+	kb.key = tcell.KeyRune
+
+	if strings.ToLower(name) == "space" {
+		kb.ch = ' '
+		return kb, nil
+	}
+
+	if utf8.RuneCountInString(name) != 1 {
+		return kb, fmt.Errorf("invalid key '%s'", key)
+	}
+
+	kb.ch, _ = utf8.DecodeRuneInString(name)
+
+	return kb, nil
+}
+
+func (kb keyBinding) String() string {
+	var name string
+
+	switch kb.key {
+	case tcell.KeyRune:
+		if kb.ch == ' ' {
+			name = "Space"
+		} else {
+			name = string(kb.ch)
+		}
+
+		if kb.mod&tcell.ModAlt != 0 {
+			name = "Alt+" + name
+		}
+
+	case tcell.KeyBackspace2:
+		name = "Backspace"
+
+	default:
+		if kb.key >= tcell.KeyCtrlA && kb.key <= tcell.KeyCtrlZ {
+			return "Ctrl+" + string(rune('a'+kb.key-tcell.KeyCtrlA))
+		}
+
+		name = tcell.KeyNames[kb.key]
+	}
+
+	return name
+}
+
+func (kb keyBinding) matches(event *tcell.EventKey) bool {
+	switch kb.key {
+	case tcell.KeyRune:
+		if event.Key() != tcell.KeyRune || event.Rune() != kb.ch {
+			return false
+		}
+
+		return event.Modifiers()&tcell.ModAlt == kb.mod&tcell.ModAlt
+
+	case tcell.KeyBackspace2:
+		return event.Key() == tcell.KeyBackspace2 ||
+			event.Key() == tcell.KeyBackspace
+	}
+
+	return event.Key() == kb.key
+}
+
+func loadKeyBindings(keys map[string][]string) error {
+	for name, bindings := range keys {
+		action := getKeyActionByName(name)
+		if action == nil {
+			return fmt.Errorf("unknown key action '%s'", name)
+		}
+
+		if action.fixed {
+			return fmt.Errorf("key action '%s' cannot be rebound", name)
+		}
+
+		var kbs []keyBinding
+
+		for _, key := range bindings {
+			kb, err := parseKey(key)
+			if err != nil {
+				return fmt.Errorf("%s: %s", name, err.Error())
+			}
+
+			kbs = append(kbs, kb)
+		}
+
+		action.keys = kbs
+	}
+
+	return nil
+}
+
+func getKeyActionByName(name string) *keyAction {
+	for _, action := range keyActions {
+		if action.name == name {
+			return action
+		}
+	}
+
+	return nil
+}
+
+func getKeyAction(ctx keyContext, event *tcell.EventKey) string {
+	for _, action := range keyActions {
+		if action.ctx != ctx || action.fixed {
+			continue
+		}
+
+		for _, kb := range action.keys {
+			if kb.matches(event) {
+				return action.name
+			}
+		}
+	}
+
+	return ""
+}
+
+// getKeyIndex returns the position of the key that triggered the named action
+// within its bindings, for actions like bookmark-jump whose keys are numbered.
+func getKeyIndex(name string, event *tcell.EventKey) int {
+	action := getKeyActionByName(name)
+	if action == nil {
+		return -1
+	}
+
+	for i, kb := range action.keys {
+		if kb.matches(event) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func getKeyNames(name string) string {
+	var names []string
+
+	action := getKeyActionByName(name)
+	if action == nil {
+		return ""
+	}
+
+	for _, kb := range action.keys {
+		names = append(names, kb.String())
+	}
+
+	return strings.Join(names, ", ")
+}
@@ -11,14 +11,16 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/gdamore/tcell/v2"
 	adb "github.com/zach-klippenstein/goadb"
 	"golang.org/x/term"
 )
 
 type sortData struct {
-	sortBy    string
-	arrangeBy string
+	sortBy     string
+	arrangeBy  string
+	natural    bool
+	ignoreCase bool
+	mixed      bool
 }
 
 var (
@@ -90,7 +92,7 @@ func (p *dirPane) isDir(testPath string) bool {
 	if mode&os.ModeSymlink != 0 {
 		switch p.mode {
 		case mAdb:
-			return isAdbSymDir(testPath, name)
+			return isAdbSymDir(testPath, name, p.getSu())
 
 		case mLocal:
 			return isLocalSymDir(testPath, name)
@@ -124,6 +126,7 @@ func (p *dirPane) localListDir(testPath string, autocomplete bool) ([]string, bo
 
 	if !autocomplete {
 		p.pathList = nil
+		p.sizes = make(map[*adb.DirEntry]int64)
 	}
 
 	for _, entry := range list {
@@ -149,6 +152,7 @@ func (p *dirPane) localListDir(testPath string, autocomplete bool) ([]string, bo
 		d.ModifiedAt = entry.ModTime()
 
 		p.pathList = append(p.pathList, &d)
+		p.sizes[&d] = entry.Size()
 	}
 
 	return dlist, true
@@ -226,6 +230,12 @@ func (p *dirPane) navigateHistory(forward bool) {
 	}
 
 	p.setPath(filepath.ToSlash(testPath))
+	p.updateFreeSpace()
+
+	if key := viewStateKey(p.mode, testPath); key != p.vkey {
+		p.loadViewState(key)
+	}
+
 	p.sortDirList(p.pathList)
 	p.row = 0
 	p.createDirList(false, false, "")
@@ -243,6 +253,16 @@ func (p *dirPane) doChangeDir(cdFwd bool, cdBack bool, tpath ...string) {
 		testPath = p.path
 	}
 
+	if p.mode == mAdb && testPath == appsLocation {
+		p.jump = ""
+
+		go app.QueueUpdateDraw(func() {
+			p.showApps(getAuxPane(p))
+		})
+
+		return
+	}
+
 	if cdFwd && p.entry != nil && p.entry.Name == ".." {
 		cdFwd = false
 		cdBack = true
@@ -273,12 +293,25 @@ func (p *dirPane) doChangeDir(cdFwd bool, cdBack bool, tpath ...string) {
 	}
 
 	if !listed {
+		p.jump = ""
 		p.setPaneSelectable(true)
 		return
 	}
 
 	p.setPath(filepath.ToSlash(testPath))
 	p.addToHistory(testPath, p.mode)
+	p.updateFreeSpace()
+
+	if key := viewStateKey(p.mode, testPath); key != p.vkey {
+		p.loadViewState(key)
+	}
+
+	if p.jump != "" {
+		p.filter = false
+		p.vcursor = p.jump
+		p.vrestore = true
+		p.jump = ""
+	}
 
 	p.sortDirList(p.pathList)
 
@@ -297,18 +330,38 @@ func (p *dirPane) ChangeDir(cdFwd, cdBack bool, tpath ...string) {
 }
 
 func (p *dirPane) ChangeDirEvent(cdFwd, cdBack bool) {
-	p.finput = ""
+	p.saveViewState()
 
 	p.ChangeDir(cdFwd, cdBack)
 }
 
+// jumpToEntry changes the pane to the directory dir and highlights the entry name.
+func (p *dirPane) jumpToEntry(mode ifaceMode, dir, name string) {
+	if mode == mAdb && !checkAdb() {
+		return
+	}
+
+	if !p.getLock() {
+		return
+	}
+
+	p.saveViewState()
+	p.setMode(mode)
+	p.jump = name
+	p.setUnlock()
+
+	p.ChangeDir(false, false, dir)
+}
+
 func resizeDirEntries(width int) {
 	if dirWidth == width {
 		return
 	}
 
+	panes := allPanes()
+
 	go func() {
-		for _, pane := range []*dirPane{selPane, auxPane} {
+		for _, pane := range panes {
 			if !pane.getLock() {
 				continue
 			}
@@ -346,7 +399,10 @@ func (p *dirPane) createDirList(cdFwd, cdBack bool, prevDir string) {
 		var pos int
 		var row int
 
-		if p.filter && (!cdFwd && !cdBack) {
+		restore := p.vrestore
+		p.vrestore = false
+
+		if p.filter && (!cdFwd && !cdBack) && !restore {
 			p.setPaneSelectable(true)
 			p.table.ScrollToBeginning()
 			return
@@ -354,7 +410,9 @@ func (p *dirPane) createDirList(cdFwd, cdBack bool, prevDir string) {
 
 		p.table.Clear()
 
-		if p.path != "/" && p.path != "" {
+		list := p.getViewList()
+
+		if p.path != "/" && p.path != "" && !p.filter {
 			parentDir := &adb.DirEntry{
 				Name: "..",
 				Mode: os.ModeDir | 0755,
@@ -363,7 +421,7 @@ func (p *dirPane) createDirList(cdFwd, cdBack bool, prevDir string) {
 			row++
 		}
 
-		totalrows := len(p.pathList)
+		totalrows := len(list)
 
 		// Calculate position once before the loop
 		if !cdFwd && !cdBack {
@@ -377,11 +435,16 @@ func (p *dirPane) createDirList(cdFwd, cdBack bool, prevDir string) {
 			}
 		}
 
-		for _, dir := range p.pathList {
+		for _, dir := range list {
 			if cdBack && (dir.Name == prevDir || dir.Name == prevDir+"/") {
 				pos = row
 			}
 
+			if restore && p.vcursor != "" && dir.Name == p.vcursor {
+				pos = row
+				cdBack = false
+			}
+
 			sel := checkSelected(p.path, dir.Name, false)
 
 			p.updateDirPane(row, sel, dir)
@@ -392,6 +455,10 @@ func (p *dirPane) createDirList(cdFwd, cdBack bool, prevDir string) {
 		p.table.Select(pos, 0)
 		p.setPaneSelectable(true)
 		p.table.ScrollToBeginning()
+
+		if p.focused {
+			updatePreview(p, true)
+		}
 	})
 }
 
@@ -434,7 +501,7 @@ func (o *operation) localOps(src, dst string) error {
 	return err
 }
 
-func formatFileSize(size int32) string {
+func formatFileSize(size int64) string {
 	if size < 0 {
 		size = 0
 	}
@@ -453,12 +520,22 @@ func formatFileSize(size int32) string {
 	}
 }
 
-func getListEntry(dir *adb.DirEntry) []string {
+// entrySize returns the size of dir. adb.DirEntry only holds 32 bits
+// of it, so the full sizes of local entries are kept in sizes.
+func entrySize(sizes map[*adb.DirEntry]int64, dir *adb.DirEntry) int64 {
+	if size, ok := sizes[dir]; ok {
+		return size
+	}
+
+	return int64(uint32(dir.Size))
+}
+
+func getListEntry(dir *adb.DirEntry, size int64) []string {
 	var sizeStr string
 	if dir.Mode.IsDir() {
 		sizeStr = "-"
 	} else {
-		sizeStr = formatFileSize(dir.Size)
+		sizeStr = formatFileSize(size)
 	}
 
 	entry := []string{
@@ -470,38 +547,51 @@ func getListEntry(dir *adb.DirEntry) []string {
 	return entry
 }
 
-func setEntryColor(col int, sel bool, perms string) (tcell.Color, tcell.AttrMask) {
+func getEntryPerms(dir *adb.DirEntry) string {
+	perms := strings.ToLower(dir.Mode.String())
+	if len(perms) > 10 {
+		perms = perms[1:]
+	}
+
+	return perms
+}
+
+func setEntryColor(col int, sel bool, perms string, dir *adb.DirEntry) themeStyle {
 	if sel {
-		return tcell.ColorOrange, tcell.AttrBold
+		return getStyle("selected")
 	}
 
 	if col > 0 {
-		return tcell.ColorDefault, tcell.AttrDim
+		return getStyle("column")
+	}
+
+	if style, ok := getLSColor(dir); ok {
+		return style
 	}
 
 	switch perms[0] {
 	case '-':
 		if strings.Contains(perms, "x") {
-			return tcell.ColorGreen, tcell.AttrNone
+			return getStyle("executable")
 		}
 
 	case 'l':
-		return tcell.ColorTeal, tcell.AttrBold
+		return getStyle("symlink")
 
 	case 'd':
-		return tcell.ColorNavy, tcell.AttrBold
+		return getStyle("directory")
 
 	case 's':
-		return tcell.ColorPurple, tcell.AttrBold
+		return getStyle("socket")
 
 	case 'p', 'c':
-		return tcell.ColorOlive, tcell.AttrBold
+		return getStyle("device")
 
 	case 'u', 't':
-		return tcell.ColorMaroon, tcell.AttrBold
+		return getStyle("special")
 	}
 
-	return tcell.ColorDefault, tcell.AttrNone
+	return getStyle("file")
 }
 
 func execCmd(cmdtext, emode, imode string) (*exec.Cmd, error) {
@@ -571,40 +661,123 @@ func execCmd(cmdtext, emode, imode string) (*exec.Cmd, error) {
 }
 
 func (p *dirPane) sortDirList(list []*adb.DirEntry) {
-	sortType, arrangeBy := p.getSortMethod()
+	sortDirEntries(list, p.getSortMethod(), p.sizes)
+}
 
-	sort.Slice(list, func(i, j int) bool {
-		var a, b int
+func sortDirEntries(list []*adb.DirEntry, method sortData, sizes map[*adb.DirEntry]int64) {
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i], list[j]
 
-		if list[i].Mode.IsDir() != list[j].Mode.IsDir() {
-			return list[i].Mode.IsDir()
+		if !method.mixed && a.Mode.IsDir() != b.Mode.IsDir() {
+			return a.Mode.IsDir()
 		}
 
-		switch arrangeBy {
-		case "asc":
-			a, b = i, j
-
-		case "desc":
-			a, b = j, i
+		if method.arrangeBy == "desc" {
+			a, b = b, a
 		}
 
-		switch sortType {
+		switch method.sortBy {
 		case "filetype":
-			if list[a].Mode.IsDir() || list[b].Mode.IsDir() {
+			if a.Mode.IsDir() || b.Mode.IsDir() {
 				break
 			}
 
-			return filepath.Ext(list[a].Name) < filepath.Ext(list[b].Name)
+			exta, extb := filepath.Ext(a.Name), filepath.Ext(b.Name)
+			if exta != extb {
+				return method.compareNames(exta, extb) < 0
+			}
 
 		case "date":
-			return list[a].ModifiedAt.Unix() < list[b].ModifiedAt.Unix()
+			if !a.ModifiedAt.Equal(b.ModifiedAt) {
+				return a.ModifiedAt.Before(b.ModifiedAt)
+			}
+
+		case "size":
+			if sa, sb := entrySize(sizes, a), entrySize(sizes, b); sa != sb {
+				return sa < sb
+			}
 		}
 
-		return list[a].Name < list[b].Name
+		return method.compareNames(a.Name, b.Name) < 0
 	})
 }
 
-func (p *dirPane) getSortMethod() (string, string) {
+func (s sortData) compareNames(a, b string) int {
+	if s.ignoreCase {
+		la, lb := strings.ToLower(a), strings.ToLower(b)
+		if la != lb {
+			a, b = la, lb
+		}
+	}
+
+	if s.natural {
+		return compareNatural(a, b)
+	}
+
+	return strings.Compare(a, b)
+}
+
+// compareNatural compares two strings treating runs of digits as numbers,
+// so that "IMG_2.jpg" sorts before "IMG_10.jpg".
+func compareNatural(a, b string) int {
+	isDigit := func(c byte) bool {
+		return c >= '0' && c <= '9'
+	}
+
+	digits := func(s string) (string, string) {
+		i := 0
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+
+		return s[:i], s[i:]
+	}
+
+	for a != "" && b != "" {
+		if !isDigit(a[0]) || !isDigit(b[0]) {
+			if a[0] != b[0] {
+				if a[0] < b[0] {
+					return -1
+				}
+
+				return 1
+			}
+
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		na, ra := digits(a)
+		nb, rb := digits(b)
+
+		ta, tb := strings.TrimLeft(na, "0"), strings.TrimLeft(nb, "0")
+
+		switch {
+		case len(ta) != len(tb):
+			if len(ta) < len(tb) {
+				return -1
+			}
+
+			return 1
+
+		case ta != tb:
+			return strings.Compare(ta, tb)
+
+		case len(na) != len(nb):
+			if len(na) > len(nb) {
+				return -1
+			}
+
+			return 1
+		}
+
+		a, b = ra, rb
+	}
+
+	return len(a) - len(b)
+}
+
+func (p *dirPane) getSortMethod() sortData {
 	sortLock.Lock()
 	defer sortLock.Unlock()
 
@@ -613,7 +786,7 @@ func (p *dirPane) getSortMethod() (string, string) {
 		p.sortMethod.arrangeBy = "asc"
 	}
 
-	return p.sortMethod.sortBy, p.sortMethod.arrangeBy
+	return p.sortMethod
 }
 
 func (p *dirPane) setSortMethod(sortby, arrangeby string) {
@@ -628,3 +801,19 @@ func (p *dirPane) setSortMethod(sortby, arrangeby string) {
 		p.sortMethod.arrangeBy = arrangeby
 	}
 }
+
+func (p *dirPane) toggleSortOption(option string) {
+	sortLock.Lock()
+	defer sortLock.Unlock()
+
+	switch option {
+	case "natural":
+		p.sortMethod.natural = !p.sortMethod.natural
+
+	case "ignorecase":
+		p.sortMethod.ignoreCase = !p.sortMethod.ignoreCase
+
+	case "mixed":
+		p.sortMethod.mixed = !p.sortMethod.mixed
+	}
+}
@@ -53,8 +53,8 @@ func setupLogView() *tview.Flex {
 	// Render initial log content directly
 	for _, entry := range logEntries {
 		timestamp := entry.timestamp.Format("15:04:05.000")
-		fmt.Fprintf(logView, "%s [::b]$ adb %s[-:-:-] - %s\n",
-			timestamp, tview.Escape(entry.command), tview.Escape(entry.output))
+		fmt.Fprintf(logView, "%s %s$ adb %s[-:-:-] - %s\n",
+			timestamp, getStyle("logcommand").tag(), tview.Escape(entry.command), tview.Escape(entry.output))
 	}
 
 	logTitle.SetText("[::b]ADB Log")
@@ -174,13 +174,8 @@ func renderLogEntries(entries []logEntry) {
 
 		for _, entry := range entries {
 			timestamp := entry.timestamp.Format("15:04:05.000")
-			color := ""
-			if entry.isError {
-				color = "red"
-			}
-
-			fmt.Fprintf(logView, "%s [%s::b]$ adb %s[-:-:-]",
-				timestamp, color, tview.Escape(entry.command))
+			fmt.Fprintf(logView, "%s %s$ adb %s[-:-:-]",
+				timestamp, logStyle(entry).tag(), tview.Escape(entry.command))
 
 			if entry.output != "" {
 				if len(entry.output) < 50 && !strings.Contains(entry.output, "\n") {
@@ -197,6 +192,14 @@ func renderLogEntries(entries []logEntry) {
 	})
 }
 
+func logStyle(entry logEntry) themeStyle {
+	if entry.isError {
+		return getStyle("logerror")
+	}
+
+	return getStyle("logcommand")
+}
+
 func showFullscreenLog() {
 	logMutex.Lock()
 	defer logMutex.Unlock()
@@ -210,13 +213,8 @@ func showFullscreenLog() {
 	} else {
 		for _, entry := range logEntries {
 			timestamp := entry.timestamp.Format("15:04:05.000")
-			color := ""
-			if entry.isError {
-				color = "red"
-			}
-
-			fmt.Fprintf(fullscreenLogView, "%s [%s::b]$ adb %s[-:-:-]",
-				timestamp, color, tview.Escape(entry.command))
+			fmt.Fprintf(fullscreenLogView, "%s %s$ adb %s[-:-:-]",
+				timestamp, logStyle(entry).tag(), tview.Escape(entry.command))
 
 			if entry.output != "" {
 				if len(entry.output) < 50 && !strings.Contains(entry.output, "\n") {
@@ -233,20 +231,13 @@ func showFullscreenLog() {
 	fullscreenLogView.ScrollToEnd()
 
 	fullscreenLogView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyEscape:
-			pages.SwitchToPage("main")
-			app.SetFocus(prevPane.table)
-			return nil
-		}
-
-		switch event.Rune() {
-		case 'l', 'q':
+		switch getKeyAction(kLog, event) {
+		case "log-exit":
 			pages.SwitchToPage("main")
 			app.SetFocus(prevPane.table)
 			return nil
 
-		case 'c':
+		case "log-clear":
 			clearLog()
 			pages.SwitchToPage("main")
 			app.SetFocus(prevPane.table)
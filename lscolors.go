@@ -0,0 +1,266 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	adb "github.com/zach-klippenstein/goadb"
+)
+
+type lsGlob struct {
+	pattern string
+	style   themeStyle
+}
+
+var (
+	lsTypes map[string]themeStyle
+	lsGlobs []lsGlob
+)
+
+func loadLSColors(env string) {
+	lsTypes = nil
+	lsGlobs = nil
+
+	if env == "" {
+		return
+	}
+
+	lsTypes = make(map[string]themeStyle)
+
+	for _, field := range strings.Split(env, ":") {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			continue
+		}
+
+		style, ok := parseSGR(kv[1])
+		if !ok {
+			continue
+		}
+
+		if strings.HasPrefix(kv[0], "*") {
+			lsGlobs = append(lsGlobs, lsGlob{kv[0], style})
+			continue
+		}
+
+		lsTypes[kv[0]] = style
+	}
+}
+
+// parseSGR converts an SGR sequence as used in LS_COLORS, for example
+// "01;38;5;208", into a style.
+//
+//gocyclo:ignore
+func parseSGR(seq string) (themeStyle, bool) {
+	style := themeStyle{
+		fg: tcell.ColorDefault,
+		bg: tcell.ColorDefault,
+	}
+
+	codes := strings.Split(seq, ";")
+
+	for i := 0; i < len(codes); i++ {
+		code, err := strconv.Atoi(codes[i])
+		if err != nil {
+			return style, false
+		}
+
+		switch {
+		case code == 0:
+			style = themeStyle{fg: tcell.ColorDefault, bg: tcell.ColorDefault}
+
+		case code == 1:
+			style.attr |= tcell.AttrBold
+
+		case code == 2:
+			style.attr |= tcell.AttrDim
+
+		case code == 3:
+			style.attr |= tcell.AttrItalic
+
+		case code == 4:
+			style.attr |= tcell.AttrUnderline
+
+		case code == 5:
+			style.attr |= tcell.AttrBlink
+
+		case code == 7:
+			style.attr |= tcell.AttrReverse
+
+		case code >= 30 && code <= 37:
+			style.fg = tcell.PaletteColor(code - 30)
+
+		case code >= 40 && code <= 47:
+			style.bg = tcell.PaletteColor(code - 40)
+
+		case code >= 90 && code <= 97:
+			style.fg = tcell.PaletteColor(code - 90 + 8)
+
+		case code >= 100 && code <= 107:
+			style.bg = tcell.PaletteColor(code - 100 + 8)
+
+		case code == 39:
+			style.fg = tcell.ColorDefault
+
+		case code == 49:
+			style.bg = tcell.ColorDefault
+
+		case code == 38 || code == 48:
+			color, n, ok := parseSGRColor(codes[i+1:])
+			if !ok {
+				return style, false
+			}
+
+			if code == 38 {
+				style.fg = color
+			} else {
+				style.bg = color
+			}
+
+			i += n
+		}
+	}
+
+	return style, true
+}
+
+func parseSGRColor(codes []string) (tcell.Color, int, bool) {
+	var values []int32
+
+	if len(codes) == 0 {
+		return tcell.ColorDefault, 0, false
+	}
+
+	for _, c := range codes {
+		v, err := strconv.Atoi(c)
+		if err != nil {
+			return tcell.ColorDefault, 0, false
+		}
+
+		values = append(values, int32(v))
+	}
+
+	switch {
+	case values[0] == 5 && len(values) >= 2:
+		return tcell.PaletteColor(int(values[1])), 2, true
+
+	case values[0] == 2 && len(values) >= 4:
+		return tcell.NewRGBColor(values[1], values[2], values[3]), 4, true
+	}
+
+	return tcell.ColorDefault, 0, false
+}
+
+// getLSColor returns the LS_COLORS style for an entry, following the
+// precedence used by ls: file type first, and extension globs only for
+// regular files without any other indicator.
+func getLSColor(dir *adb.DirEntry) (themeStyle, bool) {
+	if lsTypes == nil {
+		return themeStyle{}, false
+	}
+
+	var code string
+
+	mode := dir.Mode
+
+	switch {
+	case mode&os.ModeSymlink != 0:
+		code = "ln"
+
+	case mode.IsDir():
+		switch {
+		case mode&os.ModeSticky != 0 && mode&0002 != 0:
+			code = "tw"
+
+		case mode&0002 != 0:
+			code = "ow"
+
+		case mode&os.ModeSticky != 0:
+			code = "st"
+
+		default:
+			code = "di"
+		}
+
+	case mode&os.ModeNamedPipe != 0:
+		code = "pi"
+
+	case mode&os.ModeSocket != 0:
+		code = "so"
+
+	case mode&os.ModeCharDevice != 0:
+		code = "cd"
+
+	case mode&os.ModeDevice != 0:
+		code = "bd"
+
+	case mode&os.ModeSetuid != 0:
+		code = "su"
+
+	case mode&os.ModeSetgid != 0:
+		code = "sg"
+
+	case mode&0111 != 0:
+		code = "ex"
+	}
+
+	for _, c := range []string{code, fallbackLSType(code)} {
+		if style, ok := lsTypes[c]; ok && c != "" {
+			return style, true
+		}
+	}
+
+	if code != "" && code != "su" && code != "sg" && code != "ex" {
+		return themeStyle{}, false
+	}
+
+	if style, ok := matchLSGlob(dir.Name); ok {
+		return style, true
+	}
+
+	if style, ok := lsTypes["fi"]; ok {
+		return style, true
+	}
+
+	return themeStyle{}, false
+}
+
+func fallbackLSType(code string) string {
+	switch code {
+	case "tw", "ow", "st":
+		return "di"
+
+	case "su", "sg":
+		return "ex"
+	}
+
+	return ""
+}
+
+func matchLSGlob(name string) (themeStyle, bool) {
+	lname := strings.ToLower(name)
+
+	// Later entries override earlier ones, as with dircolors.
+	for i := len(lsGlobs) - 1; i >= 0; i-- {
+		glob := lsGlobs[i]
+		pattern := glob.pattern[1:]
+
+		if !strings.ContainsAny(pattern, "*?[") {
+			if strings.HasSuffix(name, pattern) ||
+				strings.HasSuffix(lname, strings.ToLower(pattern)) {
+				return glob.style, true
+			}
+
+			continue
+		}
+
+		if ok, _ := filepath.Match(glob.pattern, name); ok {
+			return glob.style, true
+		}
+	}
+
+	return themeStyle{}, false
+}
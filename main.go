@@ -17,55 +17,117 @@ var (
 	initAuxPath string
 	initSelMode ifaceMode
 	initAuxMode ifaceMode
+	initSort    sortData
+	initHidden  bool
 )
 
 func main() {
 	cmdAPath := kingpin.Arg("remote-path", "Remote (ADB) path to start in").
 		Default("/sdcard").String()
 
+	cmdLPath := kingpin.Flag("local-path", "Local path to start in (default: current directory)").
+		Short('l').String()
+
+	cmdLeft := kingpin.Flag("left", "Mode of the left pane").
+		Default("local").Enum("local", "adb")
+
+	cmdRight := kingpin.Flag("right", "Mode of the right pane").
+		Default("adb").Enum("local", "adb")
+
+	cmdSort := kingpin.Flag("sort", "Sort entries by").
+		Default("name").Enum("name", "filetype", "date", "size")
+
+	cmdDesc := kingpin.Flag("desc", "Sort entries in descending order").
+		Bool()
+
+	cmdHidden := kingpin.Flag("hidden", "Show hidden files").
+		Bool()
+
+	cmdRestore := kingpin.Flag("restore", "Restore the panes, history and selections of the last session").
+		Bool()
+
 	kingpin.Parse()
 
-	cwd, _ := os.Getwd()
-	cmdLPath := cwd
+	if err := loadConfig(); err != nil {
+		fmt.Printf("adbtuifm: %s\n", err.Error())
+		return
+	}
 
-	_, err := os.Lstat(cmdLPath)
-	if err != nil {
-		fmt.Printf("adbtuifm: %s: Invalid local path\n", cmdLPath)
+	loadViewStates()
+
+	if err := loadBookmarks(); err != nil {
+		fmt.Printf("adbtuifm: %s\n", err.Error())
 		return
 	}
 
-	initSelMode = mLocal
-	initSelPath, _ = filepath.Abs(cmdLPath)
+	localPath := *cmdLPath
+	if localPath == "" {
+		localPath, _ = os.Getwd()
+	}
 
-	device, err := getAdb()
-	if device == nil {
-		fmt.Printf("adbtuifm: No ADB device connected\n")
+	localPath, err := filepath.Abs(localPath)
+	if err != nil {
+		fmt.Printf("adbtuifm: %s: Invalid local path\n", localPath)
 		return
 	}
 
+	stat, err := os.Stat(localPath)
+	if err != nil || !stat.IsDir() {
+		fmt.Printf("adbtuifm: %s: Invalid local path\n", localPath)
+		return
+	}
+
+	initSelMode, _ = parseIfaceMode(*cmdLeft)
+	initAuxMode, _ = parseIfaceMode(*cmdRight)
+
 	// Make remote path relative to /sdcard if it doesn't start with /
 	adbPath := *cmdAPath
 	if len(adbPath) > 0 && adbPath[0] != '/' {
 		adbPath = filepath.Join("/sdcard", adbPath)
 	}
 
-	_, err = adbStat(device, adbPath)
-	if err != nil {
-		fmt.Printf("adbtuifm: %s: Invalid remote path\n", adbPath)
-		return
-	}
+	if initSelMode == mAdb || initAuxMode == mAdb {
+		device, _ := getAdb()
+		if device == nil {
+			fmt.Printf("adbtuifm: No ADB device connected\n")
+			return
+		}
 
-	initAuxMode = mAdb
-	initAuxPath = adbPath
+		_, err = adbStat(device, adbPath, false)
+		if err != nil {
+			fmt.Printf("adbtuifm: %s: Invalid remote path\n", adbPath)
+			return
+		}
+	}
 
 	initAPath = adbPath
-	initLPath, _ = filepath.Abs(cmdLPath)
+	initLPath = localPath
+
+	initSelPath = getInitPath(initSelMode)
+	initAuxPath = getInitPath(initAuxMode)
+
+	initSort = sortData{
+		sortBy:    *cmdSort,
+		arrangeBy: "asc",
+	}
+	if *cmdDesc {
+		initSort.arrangeBy = "desc"
+	}
+
+	initHidden = *cmdHidden
 
 	jobNum = 0
 	selected = false
-	openFiles = make(map[string]struct{})
 	multiselection = make(map[string]ifaceMode)
 
+	if *cmdRestore || config.RestoreSession {
+		lastSession, err = loadSession()
+		if err != nil {
+			fmt.Printf("adbtuifm: %s\n", err.Error())
+			return
+		}
+	}
+
 	sig := make(chan os.Signal, 1)
 	signal.Notify(
 		sig,
@@ -87,3 +149,11 @@ func main() {
 
 	setupUI()
 }
+
+func getInitPath(mode ifaceMode) string {
+	if mode == mAdb {
+		return initAPath
+	}
+
+	return initLPath
+}
@@ -0,0 +1,690 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type exifValue struct {
+	text string
+	nums []float64
+	rats [][2]uint32
+}
+
+const (
+	maxImageHeader = 256 * 1024
+	maxMp4Moov     = 8 * 1024 * 1024
+)
+
+// getImageInfo reports the dimensions of an image, along with the
+// Exif tags of JPEG photos. The header of a JPEG with a large Exif
+// thumbnail may not fit in head, in which case more of it is read.
+func getImageInfo(head []byte, rng previewRange) ([]fileInfoField, bool) {
+	var fields []fileInfoField
+
+	isjpeg := bytes.HasPrefix(head, []byte{0xff, 0xd8, 0xff})
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(head))
+	if err != nil && isjpeg && len(head) < maxImageHeader {
+		if data, rerr := rng(0, maxImageHeader); rerr == nil {
+			head = data
+			cfg, format, err = image.DecodeConfig(bytes.NewReader(head))
+		}
+	}
+
+	if err != nil {
+		w, h, ok := getWebpSize(head)
+		if !ok {
+			return nil, false
+		}
+
+		cfg.Width, cfg.Height, format = w, h, "webp"
+	}
+
+	fields = append(fields,
+		fileInfoField{"Format", format},
+		fileInfoField{"Dimensions", fmt.Sprintf("%dx%d", cfg.Width, cfg.Height)},
+	)
+
+	if isjpeg {
+		fields = append(fields, getExifFields(head)...)
+	}
+
+	return fields, true
+}
+
+func getWebpSize(data []byte) (int, int, bool) {
+	if len(data) < 30 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return 0, 0, false
+	}
+
+	le24 := func(b []byte) int {
+		return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+	}
+
+	switch string(data[12:16]) {
+	case "VP8X":
+		return le24(data[24:]) + 1, le24(data[27:]) + 1, true
+
+	case "VP8 ":
+		return int(binary.LittleEndian.Uint16(data[26:]) & 0x3fff),
+			int(binary.LittleEndian.Uint16(data[28:]) & 0x3fff), true
+
+	case "VP8L":
+		bits := binary.LittleEndian.Uint32(data[21:])
+		return int(bits&0x3fff) + 1, int(bits>>14&0x3fff) + 1, true
+	}
+
+	return 0, 0, false
+}
+
+// getExifFields extracts the camera, exposure, date and location
+// tags from the Exif segment of a JPEG.
+func getExifFields(data []byte) []fileInfoField {
+	var fields []fileInfoField
+
+	tags := parseExif(data)
+	if tags == nil {
+		return nil
+	}
+
+	text := func(tag uint16) string {
+		return strings.TrimSpace(tags[tag].text)
+	}
+
+	add := func(name, value string) {
+		if value != "" {
+			fields = append(fields, fileInfoField{name, value})
+		}
+	}
+
+	camera := text(0x010f) + " " + text(0x0110)
+	add("Camera", strings.TrimSpace(camera))
+
+	if date := text(0x9003); date != "" {
+		add("Taken", date)
+	} else {
+		add("Taken", text(0x0132))
+	}
+
+	if v := tags[0x829a]; len(v.rats) > 0 && v.rats[0][1] != 0 {
+		if v.rats[0][0] < v.rats[0][1] && v.rats[0][0] != 0 {
+			add("Exposure", fmt.Sprintf("1/%.0f s", float64(v.rats[0][1])/float64(v.rats[0][0])))
+		} else {
+			add("Exposure", fmt.Sprintf("%g s", v.nums[0]))
+		}
+	}
+
+	if v := tags[0x829d]; len(v.nums) > 0 {
+		add("Aperture", fmt.Sprintf("f/%.1f", v.nums[0]))
+	}
+
+	if v := tags[0x8827]; len(v.nums) > 0 {
+		add("ISO", strconv.Itoa(int(v.nums[0])))
+	}
+
+	if v := tags[0x920a]; len(v.nums) > 0 {
+		add("Focal length", fmt.Sprintf("%g mm", v.nums[0]))
+	}
+
+	if v := tags[0x0112]; len(v.nums) > 0 {
+		add("Orientation", exifOrientation(int(v.nums[0])))
+	}
+
+	add("Software", text(0x0131))
+
+	lat, lon := tags[0x0002], tags[0x0004]
+	if len(lat.nums) == 3 && len(lon.nums) == 3 {
+		deg := func(v exifValue, ref uint16, neg string) float64 {
+			d := v.nums[0] + v.nums[1]/60 + v.nums[2]/3600
+			if text(ref) == neg {
+				d = -d
+			}
+
+			return d
+		}
+
+		add("Location", fmt.Sprintf("%.6f, %.6f",
+			deg(lat, 0x0001, "S"), deg(lon, 0x0003, "W")))
+	}
+
+	return fields
+}
+
+func exifOrientation(o int) string {
+	switch o {
+	case 1:
+		return "normal"
+
+	case 3:
+		return "rotated 180°"
+
+	case 6:
+		return "rotated 90° CW"
+
+	case 8:
+		return "rotated 90° CCW"
+	}
+
+	return strconv.Itoa(o)
+}
+
+// parseExif reads the tags of the first image directory of the
+// Exif segment of a JPEG, and of its Exif and GPS directories.
+func parseExif(data []byte) map[uint16]exifValue {
+	var tiff []byte
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			return nil
+		}
+
+		marker := data[i+1]
+		if marker == 0xda || marker == 0xd9 {
+			return nil
+		}
+
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		if end > len(data) {
+			end = len(data)
+		}
+
+		if marker == 0xe1 && i+10 <= end && string(data[i+4:i+10]) == "Exif\x00\x00" {
+			tiff = data[i+10 : end]
+			break
+		}
+
+		i = end
+	}
+
+	if len(tiff) < 8 {
+		return nil
+	}
+
+	var bo binary.ByteOrder
+
+	switch string(tiff[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+
+	case "MM":
+		bo = binary.BigEndian
+
+	default:
+		return nil
+	}
+
+	tags := make(map[uint16]exifValue)
+
+	var readIFD func(off uint32, depth int)
+	readIFD = func(off uint32, depth int) {
+		if depth > 2 || int64(off)+2 > int64(len(tiff)) {
+			return
+		}
+
+		count := int(bo.Uint16(tiff[off:]))
+
+		for i := 0; i < count; i++ {
+			e := int(off) + 2 + i*12
+			if e+12 > len(tiff) {
+				return
+			}
+
+			tag := bo.Uint16(tiff[e:])
+
+			if tag == 0x8769 || tag == 0x8825 {
+				readIFD(bo.Uint32(tiff[e+8:]), depth+1)
+				continue
+			}
+
+			if v, ok := readExifValue(tiff, bo, tiff[e:e+12]); ok {
+				tags[tag] = v
+			}
+		}
+	}
+
+	readIFD(bo.Uint32(tiff[4:]), 0)
+
+	return tags
+}
+
+func readExifValue(tiff []byte, bo binary.ByteOrder, entry []byte) (exifValue, bool) {
+	var v exifValue
+	var unit int
+
+	typ := bo.Uint16(entry[2:])
+	count := int(bo.Uint32(entry[4:]))
+
+	switch typ {
+	case 2:
+		unit = 1
+
+	case 3:
+		unit = 2
+
+	case 4:
+		unit = 4
+
+	case 5, 10:
+		unit = 8
+
+	default:
+		return v, false
+	}
+
+	if count <= 0 || count > 1024 {
+		return v, false
+	}
+
+	size := unit * count
+
+	data := entry[8:12]
+	if size > 4 {
+		off := int(bo.Uint32(entry[8:]))
+		if off < 0 || off+size > len(tiff) {
+			return v, false
+		}
+
+		data = tiff[off : off+size]
+	}
+
+	for i := 0; i < count; i++ {
+		switch typ {
+		case 2:
+			v.text = strings.TrimRight(string(data[:size]), "\x00")
+			return v, true
+
+		case 3:
+			v.nums = append(v.nums, float64(bo.Uint16(data[i*2:])))
+
+		case 4:
+			v.nums = append(v.nums, float64(bo.Uint32(data[i*4:])))
+
+		case 5, 10:
+			num, den := bo.Uint32(data[i*8:]), bo.Uint32(data[i*8+4:])
+			v.rats = append(v.rats, [2]uint32{num, den})
+
+			if den == 0 {
+				v.nums = append(v.nums, 0)
+			} else if typ == 10 {
+				v.nums = append(v.nums, float64(int32(num))/float64(int32(den)))
+			} else {
+				v.nums = append(v.nums, float64(num)/float64(den))
+			}
+		}
+	}
+
+	return v, true
+}
+
+// getMediaInfo reports the duration and codecs of MP4/MOV,
+// WAV, FLAC and MP3 files.
+func getMediaInfo(path string, head []byte, rng previewRange, size int64) ([]fileInfoField, bool) {
+	switch {
+	case len(head) >= 12 && string(head[4:8]) == "ftyp":
+		return getMp4Info(head, rng), true
+
+	case len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WAVE":
+		return getWavInfo(head), true
+
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return getFlacInfo(head), true
+
+	case bytes.HasPrefix(head, []byte("ID3")), strings.EqualFold(filepath.Ext(path), ".mp3"):
+		return getMp3Info(head, rng, size)
+	}
+
+	return nil, false
+}
+
+func formatDuration(secs float64) string {
+	d := time.Duration(secs * float64(time.Second)).Round(time.Second)
+
+	h, m, s := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+// mp4Boxes calls fn with the type and body of each box in data.
+// A box cut off at the end of data is passed with what is present.
+func mp4Boxes(data []byte, fn func(typ string, body []byte)) {
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data))
+		typ := string(data[4:8])
+		hdr := uint64(8)
+
+		switch size {
+		case 0:
+			size = uint64(len(data))
+
+		case 1:
+			if len(data) < 16 {
+				return
+			}
+
+			size = binary.BigEndian.Uint64(data[8:])
+			hdr = 16
+		}
+
+		if size < hdr {
+			return
+		}
+
+		if size > uint64(len(data)) {
+			size = uint64(len(data))
+		}
+
+		fn(typ, data[hdr:size])
+		data = data[size:]
+	}
+}
+
+// getMp4Info locates the moov box among the top-level boxes, reading
+// only their headers, and reports the duration and the format of each
+// track. The moov box is often stored after the media data.
+func getMp4Info(head []byte, rng previewRange) []fileInfoField {
+	var fields []fileInfoField
+	var moov []byte
+	var off int64
+
+	fields = append(fields, fileInfoField{"Brand", strings.TrimSpace(string(head[8:12]))})
+
+	for i := 0; i < 64 && moov == nil; i++ {
+		hdr := make([]byte, 16)
+
+		if off+16 <= int64(len(head)) {
+			copy(hdr, head[off:])
+		} else {
+			data, err := rng(off, 16)
+			if err != nil || len(data) < 8 {
+				break
+			}
+
+			copy(hdr, data)
+		}
+
+		size := int64(binary.BigEndian.Uint32(hdr))
+		typ := string(hdr[4:8])
+		hlen := int64(8)
+
+		if size == 1 {
+			size = int64(binary.BigEndian.Uint64(hdr[8:]))
+			hlen = 16
+		}
+
+		if size < hlen {
+			break
+		}
+
+		if typ == "moov" {
+			if size > maxMp4Moov {
+				break
+			}
+
+			if off+size <= int64(len(head)) {
+				moov = head[off+hlen : off+size]
+			} else if data, err := rng(off+hlen, int(size-hlen)); err == nil {
+				moov = data
+			}
+
+			break
+		}
+
+		off += size
+	}
+
+	if moov == nil {
+		return fields
+	}
+
+	mp4Boxes(moov, func(typ string, body []byte) {
+		switch typ {
+		case "mvhd":
+			if secs, ok := getMp4Duration(body); ok {
+				fields = append(fields, fileInfoField{"Duration", formatDuration(secs)})
+			}
+
+		case "trak":
+			if name, value := getMp4Track(body); name != "" {
+				fields = append(fields, fileInfoField{name, value})
+			}
+		}
+	})
+
+	return fields
+}
+
+func getMp4Duration(mvhd []byte) (float64, bool) {
+	var scale, duration uint64
+
+	switch {
+	case len(mvhd) >= 20 && mvhd[0] == 0:
+		scale = uint64(binary.BigEndian.Uint32(mvhd[12:]))
+		duration = uint64(binary.BigEndian.Uint32(mvhd[16:]))
+
+	case len(mvhd) >= 32 && mvhd[0] == 1:
+		scale = uint64(binary.BigEndian.Uint32(mvhd[20:]))
+		duration = binary.BigEndian.Uint64(mvhd[24:])
+
+	default:
+		return 0, false
+	}
+
+	if scale == 0 {
+		return 0, false
+	}
+
+	return float64(duration) / float64(scale), true
+}
+
+// getMp4Track describes a track by its handler and first sample entry.
+func getMp4Track(trak []byte) (string, string) {
+	var handler string
+	var entry []byte
+
+	var walk func(data []byte)
+	walk = func(data []byte) {
+		mp4Boxes(data, func(typ string, body []byte) {
+			switch typ {
+			case "mdia", "minf", "stbl":
+				walk(body)
+
+			case "hdlr":
+				if len(body) >= 12 {
+					handler = string(body[8:12])
+				}
+
+			case "stsd":
+				if len(body) >= 16 {
+					entry = body[8:]
+				}
+			}
+		})
+	}
+
+	walk(trak)
+
+	if len(entry) < 8 {
+		return "", ""
+	}
+
+	codec := strings.TrimSpace(string(entry[4:8]))
+
+	switch handler {
+	case "vide":
+		if len(entry) >= 36 {
+			w := binary.BigEndian.Uint16(entry[32:])
+			h := binary.BigEndian.Uint16(entry[34:])
+
+			return "Video", fmt.Sprintf("%s %dx%d", codec, w, h)
+		}
+
+		return "Video", codec
+
+	case "soun":
+		if len(entry) >= 36 {
+			ch := binary.BigEndian.Uint16(entry[24:])
+			rate := binary.BigEndian.Uint16(entry[32:])
+
+			return "Audio", fmt.Sprintf("%s, %d ch, %d Hz", codec, ch, rate)
+		}
+
+		return "Audio", codec
+	}
+
+	return "", ""
+}
+
+func getWavInfo(head []byte) []fileInfoField {
+	var fields []fileInfoField
+	var byteRate uint32
+
+	for data := head[12:]; len(data) >= 8; {
+		id := string(data[:4])
+		size := int(binary.LittleEndian.Uint32(data[4:]))
+		body := data[8:]
+
+		switch id {
+		case "fmt ":
+			if len(body) < 16 {
+				return fields
+			}
+
+			format := binary.LittleEndian.Uint16(body)
+			channels := binary.LittleEndian.Uint16(body[2:])
+			rate := binary.LittleEndian.Uint32(body[4:])
+			byteRate = binary.LittleEndian.Uint32(body[8:])
+			bits := binary.LittleEndian.Uint16(body[14:])
+
+			codec := fmt.Sprintf("format 0x%04x", format)
+			switch format {
+			case 1:
+				codec = "PCM"
+
+			case 3:
+				codec = "IEEE float"
+
+			case 0xfffe:
+				codec = "extensible"
+			}
+
+			fields = append(fields, fileInfoField{"Audio",
+				fmt.Sprintf("%s, %d ch, %d Hz, %d bit", codec, channels, rate, bits)})
+
+		case "data":
+			if byteRate > 0 {
+				fields = append(fields, fileInfoField{"Duration",
+					formatDuration(float64(uint32(size)) / float64(byteRate))})
+			}
+
+			return fields
+		}
+
+		if size < 0 || size+size%2 > len(body) {
+			break
+		}
+
+		data = body[size+size%2:]
+	}
+
+	return fields
+}
+
+func getFlacInfo(head []byte) []fileInfoField {
+	if len(head) < 8+34 || head[4]&0x7f != 0 {
+		return nil
+	}
+
+	b := head[8:]
+
+	rate := int(b[10])<<12 | int(b[11])<<4 | int(b[12])>>4
+	channels := int(b[12]>>1&0x07) + 1
+	bits := int(b[12]&0x01)<<4 | int(b[13]>>4) + 1
+	samples := uint64(b[13]&0x0f)<<32 | uint64(binary.BigEndian.Uint32(b[14:]))
+
+	fields := []fileInfoField{
+		{"Audio", fmt.Sprintf("FLAC, %d ch, %d Hz, %d bit", channels, rate, bits)},
+	}
+
+	if rate > 0 && samples > 0 {
+		fields = append(fields, fileInfoField{"Duration", formatDuration(float64(samples) / float64(rate))})
+	}
+
+	return fields
+}
+
+// getMp3Info reads the first frame header after the ID3 tag. The
+// duration is estimated from its bitrate, as for a constant bitrate.
+func getMp3Info(head []byte, rng previewRange, size int64) ([]fileInfoField, bool) {
+	var off int64
+
+	if len(head) >= 10 && bytes.HasPrefix(head, []byte("ID3")) {
+		off = int64(head[6]&0x7f)<<21 | int64(head[7]&0x7f)<<14 | int64(head[8]&0x7f)<<7 | int64(head[9]&0x7f) + 10
+		if head[5]&0x10 != 0 {
+			off += 10
+		}
+	}
+
+	frame := head[min(off, int64(len(head))):]
+	if len(frame) < 4 {
+		data, err := rng(off, 4)
+		if err != nil || len(data) < 4 {
+			return nil, false
+		}
+
+		frame = data
+	}
+
+	if frame[0] != 0xff || frame[1]&0xe0 != 0xe0 || frame[1]>>1&0x03 != 1 {
+		return nil, false
+	}
+
+	mpeg1 := []int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320}
+	mpeg2 := []int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160}
+	rates := []int{44100, 48000, 32000}
+
+	version := frame[1] >> 3 & 0x03
+	bindex := int(frame[2] >> 4)
+	rindex := int(frame[2] >> 2 & 0x03)
+
+	if version == 1 || bindex == 0 || bindex == 15 || rindex == 3 {
+		return nil, false
+	}
+
+	bitrate, rate := mpeg1[bindex], rates[rindex]
+	switch version {
+	case 2:
+		bitrate, rate = mpeg2[bindex], rate/2
+
+	case 0:
+		bitrate, rate = mpeg2[bindex], rate/4
+	}
+
+	channels := 2
+	if frame[3]>>6 == 3 {
+		channels = 1
+	}
+
+	fields := []fileInfoField{
+		{"Audio", fmt.Sprintf("MP3, %d ch, %d Hz, %d kbps", channels, rate, bitrate)},
+	}
+
+	if size > off {
+		secs := float64(size-off) * 8 / float64(bitrate*1000)
+		fields = append(fields, fileInfoField{"Duration", "~" + formatDuration(secs)})
+	}
+
+	return fields, true
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// maxScanBroadcasts is the number of broadcasts sent per shell command.
+const maxScanBroadcasts = 50
+
+// getScanPaths returns the paths on the device which are removed and
+// added by a transfer, for the media store to rescan.
+func getScanPaths(opmode opsMode, transfer transferMode, src, dst string) ([]string, []string) {
+	switch transfer {
+	case localToAdb:
+		return nil, []string{dst}
+
+	case adbToLocal:
+		if opmode == opMove {
+			return []string{src}, nil
+		}
+
+	case adbToAdb:
+		switch opmode {
+		case opCopy:
+			return nil, []string{dst}
+
+		case opMove, opRename:
+			return []string{src}, []string{dst}
+
+		case opDelete:
+			return []string{src}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// listScanFiles returns the files at paths on the device. The files of
+// directories are listed one by one, and other paths as they are.
+func listScanFiles(paths []string, su bool) []string {
+	var files []string
+
+	device, err := getAdb()
+	if err != nil {
+		return paths
+	}
+
+	for _, path := range paths {
+		cmd := wrapAdbCommand(path, "find "+shellQuote(findPath(path))+" -type f 2>/dev/null", su)
+
+		out, err := device.RunCommand(cmd)
+		if err != nil || strings.TrimSpace(out) == "" {
+			files = append(files, path)
+			continue
+		}
+
+		for _, file := range strings.Split(out, "\n") {
+			if file = strings.TrimRight(file, "\r"); file != "" {
+				files = append(files, filepath.Clean(file))
+			}
+		}
+	}
+
+	return files
+}
+
+// mediaScan broadcasts a media scan for the removed files and the files
+// at the added paths, so that gallery and music apps pick up the changes.
+// The removed files have to be listed before the operation, since they
+// no longer exist by now; the media store drops them when scanned.
+func mediaScan(removed, added []string, su bool) {
+	device, err := getAdb()
+	if err != nil {
+		return
+	}
+
+	files := append(removed, listScanFiles(added, su)...)
+
+	for i := 0; i < len(files); i += maxScanBroadcasts {
+		var cmds []string
+
+		end := i + maxScanBroadcasts
+		if end > len(files) {
+			end = len(files)
+		}
+
+		for _, file := range files[i:end] {
+			uri := (&url.URL{Scheme: "file", Path: file}).String()
+
+			cmds = append(cmds, fmt.Sprintf(
+				"am broadcast -a android.intent.action.MEDIA_SCANNER_SCAN_FILE -d %s >/dev/null",
+				shellQuote(uri),
+			))
+		}
+
+		if _, err := runAdbShellCommand(device, strings.Join(cmds, "; ")); err != nil {
+			showErrorMsg(fmt.Errorf("Unable to scan media: %s", err.Error()), false)
+			return
+		}
+	}
+}
@@ -22,8 +22,9 @@ var popup popupModal
 
 //gocyclo:ignore
 func changeDirSelect(pane *dirPane, input *tview.InputField) {
-	var cdfilter, cdrefresh bool
+	var cdfilter, cdrefresh, closed bool
 	var entries, entrycache []string
+	var volumes []volume
 
 	dirpath := filepath.Dir(pane.getPath())
 
@@ -64,6 +65,15 @@ func changeDirSelect(pane *dirPane, input *tview.InputField) {
 			}
 		}
 
+		for _, vol := range volumes {
+			cell := tview.NewTableCell("[::b]" + tview.Escape(vol.path) + "[::-] (" + tview.Escape(vol.String()) + ")")
+
+			cell.SetReference(vol.path)
+			cdtable.SetCell(row, 0, cell.SetTextColor(tcell.ColorDefault))
+
+			row++
+		}
+
 		if row == 0 {
 			pages.HidePage("cdmodal")
 		} else {
@@ -144,28 +154,40 @@ func changeDirSelect(pane *dirPane, input *tview.InputField) {
 		}
 	})
 
+	exit := func() {
+		closed = true
+
+		popupStatus(false)
+		pages.SwitchToPage("main")
+		statuspgs.SwitchToPage("statusmsg")
+		app.SetFocus(pane.table)
+	}
+
 	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyTab:
+		switch getKeyAction(kChangeDir, event) {
+		case "cd-complete":
 			autocompletefunc(input.GetText(), false)
+			return event
 
-		case tcell.KeyEnter:
+		case "cd-select":
 			infomsg(input.GetText())
+			pane.saveViewState()
 			pane.ChangeDir(false, false, input.GetText())
-			fallthrough
+			exit()
+			return event
 
-		case tcell.KeyEscape:
-			popupStatus(false)
-			pages.SwitchToPage("main")
-			statuspgs.SwitchToPage("statusmsg")
-			app.SetFocus(pane.table)
+		case "cd-exit":
+			exit()
+			return event
 
-		case tcell.KeyCtrlW:
+		case "cd-up":
 			text := trimPath(input.GetText(), true)
 			input.SetText(text)
 			autocompletefunc(text, true)
 			return nil
+		}
 
+		switch event.Key() {
 		case tcell.KeyDown, tcell.KeyUp:
 			cdfilter = true
 			fallthrough
@@ -213,6 +235,22 @@ func changeDirSelect(pane *dirPane, input *tview.InputField) {
 	pages.AddPage("cdmodal", statusmodal(flex, cdtable), true, false).ShowPage("main")
 
 	autocompletefunc(pane.getPath(), false)
+
+	go func() {
+		vols, err := getVolumes(pane.mode)
+		if err != nil || vols == nil {
+			return
+		}
+
+		go app.QueueUpdateDraw(func() {
+			if closed {
+				return
+			}
+
+			volumes = vols
+			reload(input.GetText(), true)
+		})
+	}()
 }
 
 //gocyclo:ignore
@@ -275,7 +313,7 @@ func editSelections(input, sinput *tview.InputField) *tview.InputField {
 	}
 
 	seltoggle := func(a, i bool) {
-		var color tcell.Color
+		var style themeStyle
 
 		pos, _ := seltable.GetSelection()
 		totalrows := seltable.GetRowCount()
@@ -302,14 +340,14 @@ func editSelections(input, sinput *tview.InputField) *tview.InputField {
 			_, ok := delpaths[selpath]
 
 			if !ok && (one || inv) {
-				color = tcell.ColorGreen
+				style = getStyle("marked")
 				delpaths[selpath] = empty
 			} else {
-				color = tcell.ColorRed
+				style = getStyle("unmarked")
 				delete(delpaths, selpath)
 			}
 
-			seltable.SetCell(row, 0, cell.SetTextColor(color))
+			seltable.SetCell(row, 0, cell.SetTextColor(style.fg))
 
 			if one {
 				if row+1 < totalrows {
@@ -325,20 +363,20 @@ func editSelections(input, sinput *tview.InputField) *tview.InputField {
 	}
 
 	markselected := func(i int, name string) {
-		var color tcell.Color
+		var style themeStyle
 
 		_, ok := delpaths[name]
 
 		if !ok {
-			color = tcell.ColorRed
+			style = getStyle("unmarked")
 		} else {
-			color = tcell.ColorGreen
+			style = getStyle("marked")
 		}
 
 		cell := tview.NewTableCell("[::b]" + tview.Escape(name))
 
 		cell.SetReference(name)
-		seltable.SetCell(i, 0, cell.SetTextColor(color))
+		seltable.SetCell(i, 0, cell.SetTextColor(style.fg))
 	}
 
 	input.SetChangedFunc(func(text string) {
@@ -405,26 +443,20 @@ func editSelections(input, sinput *tview.InputField) *tview.InputField {
 	})
 
 	seltable.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyEscape:
+		switch getKeyAction(kEditSel, event) {
+		case "edit-cancel":
 			exit()
 
-		case tcell.KeyCtrlS:
+		case "edit-save":
 			save()
-		}
 
-		if event.Modifiers() != tcell.ModAlt {
-			return event
-		}
-
-		switch event.Rune() {
-		case ' ':
+		case "edit-select-one":
 			seltoggle(false, false)
 
-		case 'a':
+		case "edit-select-invert":
 			seltoggle(false, true)
 
-		case 'A':
+		case "edit-select-all":
 			seltoggle(true, false)
 		}
 
@@ -455,7 +487,7 @@ func editSelections(input, sinput *tview.InputField) *tview.InputField {
 		cell := tview.NewTableCell("[::b]" + tview.Escape(spath))
 
 		cell.SetReference(spath)
-		seltable.SetCell(row, 0, cell.SetTextColor(tcell.ColorRed))
+		seltable.SetCell(row, 0, cell.SetTextColor(getStyle("unmarked").fg))
 
 		row++
 	}
@@ -541,9 +573,10 @@ var progDialog progressDialog
 func setupProgressDialog() {
 	progDialog.table = tview.NewTable()
 	progDialog.table.SetSelectable(false, false)
-	progDialog.table.SetBackgroundColor(tcell.ColorDefault)
+	progDialog.table.SetBackgroundColor(getStyle("progress").bg)
 	progDialog.table.SetBorder(true)
-	progDialog.table.SetBorderColor(tcell.ColorDefault)
+	progDialog.table.SetBorderColor(getStyle("progress").fg)
+	progDialog.table.SetTitleColor(getStyle("progress").fg)
 	progDialog.table.SetTitle(" Operation Progress ")
 
 	modalHeight := 6
@@ -623,7 +656,7 @@ func updateProgressDialog() {
 			tview.NewTableCell(text).
 				SetExpansion(1).
 				SetAlign(tview.AlignLeft).
-				SetTextColor(tcell.ColorDefault).
+				SetStyle(getStyle("progress").style()).
 				SetSelectable(false))
 		row++
 
@@ -636,7 +669,7 @@ func updateProgressDialog() {
 			tview.NewTableCell(prog).
 				SetExpansion(1).
 				SetAlign(tview.AlignLeft).
-				SetTextColor(tcell.ColorDefault).
+				SetStyle(getStyle("progress").style()).
 				SetSelectable(false))
 		row++
 
@@ -645,7 +678,7 @@ func updateProgressDialog() {
 
 		hint := tview.NewTableCell("[::d]Press [::b]o[::d] for full operations view").
 			SetAlign(tview.AlignCenter).
-			SetTextColor(tcell.ColorDefault).
+			SetStyle(getStyle("progress").style()).
 			SetSelectable(false)
 		progDialog.table.SetCell(row, 0, hint)
 	})
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/darkhz/tview"
+	"github.com/gdamore/tcell/v2"
+)
+
+// fileOpener is a program used to open files. Files are matched by
+// extension (".txt"), name glob ("*.log") or MIME type ("image/*").
+type fileOpener struct {
+	Name     string   `json:"name"`
+	Command  string   `json:"command"`
+	Match    []string `json:"match"`
+	Wait     bool     `json:"wait"`
+	Terminal bool     `json:"terminal"`
+}
+
+// getOpeners returns the configured openers, followed by $VISUAL
+// or $EDITOR for text files and the system's default opener.
+func getOpeners() []fileOpener {
+	var openers []fileOpener
+
+	for _, o := range config.Openers {
+		if o.Name == "" {
+			o.Name = o.Command
+		}
+
+		openers = append(openers, o)
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+
+	if editor != "" {
+		openers = append(openers, fileOpener{
+			Name:     "Editor (" + editor + ")",
+			Command:  editor,
+			Match:    []string{"text/*"},
+			Terminal: true,
+		})
+	}
+
+	if runtime.GOOS == "darwin" {
+		openers = append(openers, fileOpener{
+			Name:    "System default (open)",
+			Command: "open -W",
+			Wait:    true,
+		})
+	} else {
+		openers = append(openers, fileOpener{
+			Name:    "System default (xdg-open)",
+			Command: "xdg-open",
+			Wait:    true,
+		})
+	}
+
+	return openers
+}
+
+// findOpener returns the first opener matching the local file at path.
+func findOpener(path string) fileOpener {
+	var mtype string
+
+	if f, err := os.Open(path); err == nil {
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(f, head)
+		f.Close()
+
+		mtype = getMimeType(path, head[:n])
+	}
+
+	openers := getOpeners()
+
+	for _, o := range openers {
+		if o.matches(filepath.Base(path), mtype) {
+			return o
+		}
+	}
+
+	return openers[len(openers)-1]
+}
+
+func (o fileOpener) matches(name, mtype string) bool {
+	if len(o.Match) == 0 {
+		return true
+	}
+
+	name = strings.ToLower(name)
+	mtype, _, _ = strings.Cut(mtype, ";")
+
+	for _, m := range o.Match {
+		m = strings.ToLower(m)
+
+		switch {
+		case strings.HasPrefix(m, "."):
+			if strings.HasSuffix(name, m) {
+				return true
+			}
+
+		case strings.Contains(m, "/"):
+			if ok, _ := filepath.Match(m, mtype); ok {
+				return true
+			}
+
+		default:
+			if ok, _ := filepath.Match(m, name); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// command returns the command line opening path. The path replaces
+// any "{}" in the command, or is appended to it.
+func (o fileOpener) command(path string) string {
+	qpath := shellQuote(path)
+
+	if strings.Contains(o.Command, "{}") {
+		return strings.ReplaceAll(o.Command, "{}", qpath)
+	}
+
+	return o.Command + " " + qpath
+}
+
+// run opens path. Terminal openers run in the foreground with the UI
+// suspended. Other openers run in the background, and are waited for
+// if they are set to wait; run reports whether the opener has exited.
+func (o fileOpener) run(path string) (bool, error) {
+	cmdtext := o.command(path)
+
+	addLog("open "+cmdtext, "", false)
+
+	cmd := exec.Command("sh", "-c", cmdtext)
+
+	if o.Terminal {
+		var err error
+
+		app.Suspend(func() {
+			cmd.Stdin = os.Stdin
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+
+			err = cmd.Run()
+		})
+
+		return true, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return false, err
+	}
+
+	if !o.Wait {
+		go cmd.Wait()
+		return false, nil
+	}
+
+	return true, cmd.Wait()
+}
+
+// showOpenWith lists the openers in a popup, to open
+// the highlighted file with the chosen one.
+func (p *dirPane) showOpenWith() {
+	p.updateRef(false)
+
+	if p.entry == nil || p.entry.Name == ".." || p.entry.Mode.IsDir() {
+		return
+	}
+
+	entry := p.entry
+	openers := getOpeners()
+
+	input := getStatusInput("Open "+tview.Escape(entry.Name)+" with:", false)
+
+	optable := tview.NewTable()
+
+	flex := tview.NewFlex().
+		AddItem(optable, 0, 10, false).
+		SetDirection(tview.FlexRow)
+
+	exit := func() {
+		popupStatus(false)
+		pages.SwitchToPage("main")
+		statuspgs.SwitchToPage("statusmsg")
+		app.SetFocus(p.table)
+	}
+
+	reload := func(text string) {
+		var row int
+
+		optable.Clear()
+
+		for _, o := range openers {
+			if !strings.Contains(strings.ToLower(o.Name+" "+o.Command), strings.ToLower(text)) {
+				continue
+			}
+
+			var flags []string
+
+			if o.Terminal {
+				flags = append(flags, "terminal")
+			} else if !o.Wait {
+				flags = append(flags, "detached")
+			}
+
+			if len(o.Match) > 0 {
+				flags = append(flags, strings.Join(o.Match, " "))
+			}
+
+			desc := o.Command
+			if len(flags) > 0 {
+				desc += ", " + strings.Join(flags, ", ")
+			}
+
+			cell := tview.NewTableCell(fmt.Sprintf("[::b]%s[::-] (%s)", tview.Escape(o.Name), tview.Escape(desc)))
+			cell.SetReference(o)
+			optable.SetCell(row, 0, cell.SetTextColor(tcell.ColorDefault))
+
+			row++
+		}
+
+		if row == 0 {
+			pages.HidePage("openwithmodal")
+		} else {
+			if pg, _ := pages.GetFrontPage(); pg != "openwithmodal" {
+				pages.SwitchToPage("openwithmodal").ShowPage("main")
+			}
+
+			resizemodal()
+		}
+
+		app.SetFocus(input)
+
+		optable.Select(0, 0)
+		optable.ScrollToBeginning()
+	}
+
+	input.SetChangedFunc(func(text string) {
+		reload(text)
+	})
+
+	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch getKeyAction(kOpenWith, event) {
+		case "openwith-select":
+			row, _ := optable.GetSelection()
+
+			o, ok := optable.GetCell(row, 0).GetReference().(fileOpener)
+			exit()
+
+			if ok {
+				go p.openFileHandler(entry, &o)
+			}
+
+			return nil
+
+		case "openwith-exit":
+			exit()
+			return nil
+		}
+
+		switch event.Key() {
+		case tcell.KeyDown, tcell.KeyUp, tcell.KeyPgDn, tcell.KeyPgUp:
+			optable.InputHandler()(event, nil)
+			return nil
+		}
+
+		return event
+	})
+
+	optable.SetSelectedStyle(getStyle("cursor").style())
+
+	optable.SetSelectable(true, false)
+	optable.SetBackgroundColor(tcell.ColorDefault)
+
+	pages.AddAndSwitchToPage("openwithmodal", statusmodal(flex, optable), true).ShowPage("main")
+
+	statuspgs.AddAndSwitchToPage("openwith", input, true)
+	app.SetFocus(input)
+
+	reload("")
+}
@@ -0,0 +1,609 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/darkhz/tview"
+	"github.com/fsnotify/fsnotify"
+	"github.com/gdamore/tcell/v2"
+)
+
+// openedFile is a local copy of a file that was opened with an opener.
+// While the copy is tracked, every change made to it is copied back to
+// the source, unless the source was changed in the meantime.
+type openedFile struct {
+	pane  *dirPane
+	mode  ifaceMode
+	path  string
+	local string
+	su    bool
+
+	opener   string
+	running  int
+	detached bool
+
+	mtime time.Time
+	size  int64
+	sum   []byte
+
+	status   string
+	conflict bool
+
+	timer   *time.Timer
+	watcher *fsnotify.Watcher
+
+	lock     sync.Mutex
+	syncLock sync.Mutex
+}
+
+const openedSyncDelay = 500 * time.Millisecond
+
+var (
+	openedFiles   []*openedFile
+	openedLock    sync.Mutex
+	openedRefresh func()
+
+	stagingDir  string
+	stagingLock sync.Mutex
+)
+
+func getStagingRoot() string {
+	if config.StagingDir != "" {
+		return config.StagingDir
+	}
+
+	return os.TempDir()
+}
+
+// getStagingPath returns the path to copy the file at path to when opening
+// it. Copies are kept in a directory private to the session, below which
+// the path of the file is mirrored per mode, so that names do not collide.
+func getStagingPath(mode ifaceMode, path string) (string, error) {
+	stagingLock.Lock()
+	defer stagingLock.Unlock()
+
+	if stagingDir == "" {
+		root := getStagingRoot()
+
+		if err := os.MkdirAll(root, 0700); err != nil {
+			return "", err
+		}
+
+		dir, err := os.MkdirTemp(root, "adbtuifm-")
+		if err != nil {
+			return "", err
+		}
+
+		stagingDir = dir
+	}
+
+	spath := filepath.Join(stagingDir, strings.ToLower(mode.String()), filepath.Clean("/"+path))
+
+	if err := os.MkdirAll(filepath.Dir(spath), 0700); err != nil {
+		return "", err
+	}
+
+	return spath, nil
+}
+
+// removeStaged removes a copy, along with the directories
+// above it that are left empty.
+func removeStaged(spath string) {
+	stagingLock.Lock()
+	defer stagingLock.Unlock()
+
+	os.Remove(spath)
+
+	for dir := filepath.Dir(spath); strings.HasPrefix(dir, stagingDir+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+}
+
+// getOpenedFile returns the tracked copy of the file at path, if any.
+func getOpenedFile(mode ifaceMode, path string) *openedFile {
+	openedLock.Lock()
+	defer openedLock.Unlock()
+
+	for _, of := range openedFiles {
+		if of.mode == mode && of.path == path {
+			return of
+		}
+	}
+
+	return nil
+}
+
+func checkOpen(fpath string) bool {
+	openedLock.Lock()
+	defer openedLock.Unlock()
+
+	for _, of := range openedFiles {
+		if of.path == fpath {
+			return true
+		}
+	}
+
+	return false
+}
+
+// trackFile starts tracking local, the copy of the file at path. The
+// directory of the copy is watched rather than the copy itself, since
+// editors often save by replacing the file.
+func trackFile(p *dirPane, mode ifaceMode, path, local string) (*openedFile, error) {
+	of := &openedFile{
+		pane:   p,
+		mode:   mode,
+		path:   path,
+		local:  local,
+		su:     mode == mAdb && p.getSu(),
+		status: "Unchanged",
+	}
+
+	var err error
+
+	of.mtime, of.size, err = statSource(mode, path, of.su)
+	if err != nil {
+		return nil, err
+	}
+
+	of.sum, err = hashFile(local)
+	if err != nil {
+		return nil, err
+	}
+
+	of.watcher, err = fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = of.watcher.Add(filepath.Dir(local)); err != nil {
+		of.watcher.Close()
+		return nil, err
+	}
+
+	openedLock.Lock()
+	openedFiles = append(openedFiles, of)
+	openedLock.Unlock()
+
+	go of.watch()
+
+	return of, nil
+}
+
+func statSource(mode ifaceMode, path string, su bool) (time.Time, int64, error) {
+	switch mode {
+	case mAdb:
+		device, err := getAdb()
+		if err != nil {
+			return time.Time{}, 0, err
+		}
+
+		stat, err := adbStat(device, path, su)
+		if err != nil {
+			return time.Time{}, 0, err
+		}
+
+		return stat.ModifiedAt, int64(uint32(stat.Size)), nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+
+	return info.ModTime(), info.Size(), nil
+}
+
+func hashFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+
+	return h.Sum(nil), nil
+}
+
+func (of *openedFile) watch() {
+	for {
+		select {
+		case event, ok := <-of.watcher.Events:
+			if !ok {
+				return
+			}
+
+			if filepath.Clean(event.Name) != of.local ||
+				event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+
+			of.lock.Lock()
+			if of.timer == nil {
+				of.timer = time.AfterFunc(openedSyncDelay, func() {
+					of.sync(false)
+				})
+			} else {
+				of.timer.Reset(openedSyncDelay)
+			}
+			of.lock.Unlock()
+
+		case err, ok := <-of.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			addLog("watch "+of.local, err.Error(), true)
+		}
+	}
+}
+
+func (of *openedFile) setStatus(status string, conflict bool) {
+	of.lock.Lock()
+	of.status = status
+	of.conflict = conflict
+	of.lock.Unlock()
+
+	refreshOpenedFiles()
+}
+
+// sync copies the local copy back to the source if it has changed. Unless
+// force is set, the source is first checked for changes made since the
+// copy was made or last copied back.
+func (of *openedFile) sync(force bool) {
+	of.syncLock.Lock()
+	defer of.syncLock.Unlock()
+
+	of.lock.Lock()
+	of.timer = nil
+	of.lock.Unlock()
+
+	name := filepath.Base(of.path)
+
+	sum, err := hashFile(of.local)
+	if err != nil {
+		// The copy may be replaced by an editor, in which
+		// case another event follows once it is written.
+		return
+	}
+
+	if !force && bytes.Equal(sum, of.sum) {
+		return
+	}
+
+	mtime, size, err := statSource(of.mode, of.path, of.su)
+	if err != nil {
+		of.setStatus("Error: "+err.Error(), false)
+		showErrorMsg(fmt.Errorf("Unable to check '%s': %s", name, err.Error()), false)
+
+		return
+	}
+
+	if !force && (!mtime.Equal(of.mtime) || size != of.size) {
+		of.setStatus("Conflict: the source was changed", true)
+		showErrorMsg(fmt.Errorf("'%s' was changed on %s, changes were not copied back", name, of.mode.String()), false)
+
+		return
+	}
+
+	of.setStatus("Copying back", false)
+
+	_, err = startOperation(
+		of.pane,
+		&dirPane{path: of.path, mode: of.mode, su: of.su},
+		opCopy,
+		true,
+		[]selection{{of.local, mLocal}},
+	)
+	if err != nil {
+		of.setStatus("Error: "+err.Error(), false)
+		showErrorMsg(fmt.Errorf("Unable to save '%s': %s", name, err.Error()), false)
+
+		return
+	}
+
+	mtime, size, err = statSource(of.mode, of.path, of.su)
+	if err == nil {
+		of.mtime, of.size = mtime, size
+	}
+
+	of.lock.Lock()
+	of.sum = sum
+	of.lock.Unlock()
+
+	of.setStatus("Copied back at "+time.Now().Format("15:04:05"), false)
+
+	showInfoMsg(fmt.Sprintf("Copied changes of '%s' back to %s", name, of.path))
+}
+
+// reload replaces the local copy with the source, discarding local changes.
+func (of *openedFile) reload() {
+	of.syncLock.Lock()
+	defer of.syncLock.Unlock()
+
+	name := filepath.Base(of.path)
+
+	of.setStatus("Reloading", false)
+
+	_, err := startOperation(
+		&dirPane{path: filepath.Dir(of.path), mode: of.mode, su: of.su},
+		&dirPane{path: of.local, mode: mLocal},
+		opCopy,
+		true,
+		[]selection{{of.path, of.mode}},
+	)
+	if err == nil {
+		of.mtime, of.size, err = statSource(of.mode, of.path, of.su)
+	}
+	if err == nil {
+		var sum []byte
+
+		sum, err = hashFile(of.local)
+
+		of.lock.Lock()
+		of.sum = sum
+		of.lock.Unlock()
+	}
+
+	if err != nil {
+		of.setStatus("Error: "+err.Error(), false)
+		showErrorMsg(fmt.Errorf("Unable to reload '%s': %s", name, err.Error()), false)
+
+		return
+	}
+
+	of.setStatus("Reloaded at "+time.Now().Format("15:04:05"), false)
+	showInfoMsg(fmt.Sprintf("Reloaded '%s' from %s", name, of.path))
+}
+
+// modified reports whether the local copy has changes which
+// were not copied back.
+func (of *openedFile) modified() bool {
+	sum, err := hashFile(of.local)
+
+	of.lock.Lock()
+	defer of.lock.Unlock()
+
+	return err == nil && !bytes.Equal(sum, of.sum)
+}
+
+// stop stops tracking the file. The local copy is removed unless keep is set.
+func (of *openedFile) stop(keep bool) {
+	openedLock.Lock()
+	for i, f := range openedFiles {
+		if f == of {
+			openedFiles = append(openedFiles[:i], openedFiles[i+1:]...)
+			break
+		}
+	}
+	openedLock.Unlock()
+
+	of.lock.Lock()
+	if of.timer != nil {
+		of.timer.Stop()
+	}
+	of.lock.Unlock()
+
+	of.watcher.Close()
+
+	if !keep {
+		removeStaged(of.local)
+	}
+}
+
+// stopOpenedFiles stops tracking all files on exit, and removes the
+// staging directory. Copies with changes which were not copied back
+// are kept, so that they are not lost.
+func stopOpenedFiles() {
+	var kept bool
+
+	openedLock.Lock()
+	files := append([]*openedFile{}, openedFiles...)
+	openedLock.Unlock()
+
+	for _, of := range files {
+		keep := of.modified()
+		if keep {
+			kept = true
+			addLog("open", fmt.Sprintf("keeping modified copy of %s at %s", of.path, of.local), true)
+		}
+
+		of.stop(keep)
+	}
+
+	stagingLock.Lock()
+	defer stagingLock.Unlock()
+
+	if stagingDir != "" && !kept {
+		os.RemoveAll(stagingDir)
+	}
+}
+
+func refreshOpenedFiles() {
+	if openedRefresh == nil {
+		return
+	}
+
+	go app.QueueUpdateDraw(func() {
+		if openedRefresh != nil {
+			openedRefresh()
+		}
+	})
+}
+
+// showOpenedFiles lists the tracked files, to copy them back,
+// reload, reopen them or stop tracking them.
+func (p *dirPane) showOpenedFiles() {
+	optable := tview.NewTable()
+	optitle := newTextView()
+
+	flex := tview.NewFlex().
+		AddItem(optitle, 1, 0, false).
+		AddItem(optable, 0, 1, true).
+		AddItem(statuspgs, 1, 0, false).
+		SetDirection(tview.FlexRow)
+
+	exit := func() {
+		openedRefresh = nil
+
+		pages.SwitchToPage("main")
+		pages.RemovePage("openedfiles")
+
+		app.SetFocus(p.table)
+	}
+
+	load := func() {
+		openedLock.Lock()
+		files := append([]*openedFile{}, openedFiles...)
+		openedLock.Unlock()
+
+		optitle.SetText(getStyle("title").tag() + fmt.Sprintf("Opened files (%d)", len(files)))
+
+		row, _ := optable.GetSelection()
+		optable.Clear()
+
+		for i, of := range files {
+			of.lock.Lock()
+
+			state := "closed"
+			switch {
+			case of.running > 0:
+				state = "open"
+
+			case of.detached:
+				state = "detached"
+			}
+
+			status, conflict := of.status, of.conflict
+			opener := of.opener + " (" + state + ")"
+
+			of.lock.Unlock()
+
+			cells := []*tview.TableCell{
+				tview.NewTableCell(of.mode.String() + " "),
+				tview.NewTableCell(tview.Escape(of.path) + " ").SetExpansion(1),
+				tview.NewTableCell(tview.Escape(opener) + " "),
+				tview.NewTableCell(tview.Escape(status) + " "),
+				tview.NewTableCell(tview.Escape(of.local)),
+			}
+
+			for col, cell := range cells {
+				cell.SetStyle(getStyle("column").style())
+				optable.SetCell(i, col, cell.SetReference(of))
+			}
+
+			optable.GetCell(i, 1).SetStyle(getStyle("file").style())
+			if conflict {
+				optable.GetCell(i, 3).SetStyle(getStyle("error").style())
+			}
+		}
+
+		optable.Select(min(row, max(len(files)-1, 0)), 0)
+	}
+
+	confirm := func(msg string, yes func()) {
+		input := getStatusInput(msg, true)
+
+		input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+			switch event.Key() {
+			case tcell.KeyEnter:
+				statuspgs.SwitchToPage("statusmsg")
+				app.SetFocus(optable)
+
+				if strings.ToLower(input.GetText()) == "y" {
+					yes()
+				}
+
+				return nil
+
+			case tcell.KeyEscape:
+				statuspgs.SwitchToPage("statusmsg")
+				app.SetFocus(optable)
+
+				return nil
+			}
+
+			return event
+		})
+
+		statuspgs.AddAndSwitchToPage("confirm", input, true)
+		app.SetFocus(input)
+	}
+
+	optable.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		action := getKeyAction(kOpened, event)
+		if action == "opened-exit" {
+			exit()
+			return nil
+		}
+
+		row, _ := optable.GetSelection()
+
+		of, ok := optable.GetCell(row, 0).GetReference().(*openedFile)
+		if !ok {
+			return event
+		}
+
+		switch action {
+		case "opened-open":
+			exit()
+			go of.pane.openTrackedFile(of, nil)
+
+			return nil
+
+		case "opened-push":
+			confirm("Copy the local copy back, overwriting the source (y/N)?", func() {
+				go of.sync(true)
+			})
+
+			return nil
+
+		case "opened-reload":
+			confirm("Reload from the source, discarding local changes (y/N)?", func() {
+				go of.reload()
+			})
+
+			return nil
+
+		case "opened-stop":
+			stop := func() {
+				of.stop(false)
+				load()
+			}
+
+			if of.modified() {
+				confirm("The local copy has changes which were not copied back. Stop tracking it (y/N)?", stop)
+			} else {
+				stop()
+			}
+
+			return nil
+		}
+
+		return event
+	})
+
+	optable.SetSelectable(true, false)
+	optable.SetBackgroundColor(tcell.ColorDefault)
+	optable.SetSelectedStyle(getStyle("cursor").style())
+
+	openedRefresh = load
+	load()
+
+	pages.AddAndSwitchToPage("openedfiles", flex, true)
+	app.SetFocus(optable)
+}
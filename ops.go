@@ -21,8 +21,8 @@ type operation struct {
 	progress   progressMode
 	ctx        context.Context
 	cancel     context.CancelFunc
-	sortBy     string
-	arrangeBy  string
+	sortMethod sortData
+	su         bool
 }
 
 type ifaceMode int
@@ -32,6 +32,27 @@ const (
 	mLocal
 )
 
+func (m ifaceMode) String() string {
+	modestr := [...]string{
+		"Adb",
+		"Local",
+	}
+
+	return modestr[m]
+}
+
+func parseIfaceMode(mode string) (ifaceMode, error) {
+	switch strings.ToLower(mode) {
+	case "adb":
+		return mAdb, nil
+
+	case "local":
+		return mLocal, nil
+	}
+
+	return mLocal, fmt.Errorf("invalid mode '%s'", mode)
+}
+
 type transferMode int
 
 const (
@@ -49,6 +70,7 @@ const (
 	opMkdir
 	opRename
 	opDelete
+	opInstall
 )
 
 func (m opsMode) String() string {
@@ -58,6 +80,7 @@ func (m opsMode) String() string {
 		"Mkdir",
 		"Rename",
 		"Delete",
+		"Install",
 	}
 
 	return opstr[m]
@@ -69,7 +92,7 @@ func opString(mode string) string {
 		mode = mode[0 : len(mode)-1]
 		fallthrough
 
-	case "Copy":
+	case "Copy", "Install":
 		mode += "ing"
 
 	default:
@@ -86,7 +109,7 @@ var (
 	opPathLock sync.Mutex
 )
 
-func newOperation(opmode opsMode, sortBy, arrangeBy string) operation {
+func newOperation(opmode opsMode, sortMethod sortData) operation {
 	transfer := localToLocal
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -97,20 +120,26 @@ func newOperation(opmode opsMode, sortBy, arrangeBy string) operation {
 		cancel:     cancel,
 		transfer:   transfer,
 		totalBytes: -1,
-		sortBy:     sortBy,
-		arrangeBy:  arrangeBy,
+		sortMethod: sortMethod,
 	}
 }
 
 func startOperation(srcPane, dstPane *dirPane, opmode opsMode, overwrite bool, mselect []selection) (string, error) {
 	var err error
 	var src, dst string
+	var scanRemoved, scanAdded []string
 
 	total := len(mselect)
 	addLog("startOperation", fmt.Sprintf("total=%d, dstPane.mode=%v", total, dstPane.mode), false)
 
-	sortBy, arrangeBy := srcPane.getSortMethod()
-	op := newOperation(opmode, sortBy, arrangeBy)
+	op := newOperation(opmode, srcPane.getSortMethod())
+
+	// Device paths are accessed with su if either pane is in root mode.
+	for _, pane := range []*dirPane{srcPane, dstPane} {
+		if pane.mode == mAdb && pane.getSu() {
+			op.su = true
+		}
+	}
 
 	op.opSetStatus(opInProgress, nil)
 
@@ -136,7 +165,7 @@ func startOperation(srcPane, dstPane *dirPane, opmode opsMode, overwrite bool, m
 		}
 
 		if opmode == opCopy && !overwrite {
-			dst, err = altPath(src, dst, dstPane.mode)
+			dst, err = altPath(src, dst, dstPane.mode, op.su)
 			if err != nil {
 				addLog("startOperation", fmt.Sprintf("altPath error: %v", err), true)
 				break
@@ -157,10 +186,21 @@ func startOperation(srcPane, dstPane *dirPane, opmode opsMode, overwrite bool, m
 		}
 		addLog("startOperation", fmt.Sprintf("progress set, totalFiles=%d totalBytes=%d", op.totalFile, op.totalBytes), false)
 
+		if err = op.checkFreeSpace(src, dst, msel.smode, dstPane.mode); err != nil {
+			break
+		}
+
 		if err = addOpsPath(src, dst); err != nil {
 			break
 		}
 
+		// Files under removed paths are listed now, while they still exist.
+		var removed, added []string
+		if config.MediaScan {
+			removed, added = getScanPaths(opmode, op.transfer, src, dst)
+			removed = listScanFiles(removed, op.su)
+		}
+
 		addLog("startOperation", "starting transfer...", false)
 		switch op.transfer {
 		case localToLocal:
@@ -176,10 +216,17 @@ func startOperation(srcPane, dstPane *dirPane, opmode opsMode, overwrite bool, m
 		if err != nil {
 			break
 		}
+
+		scanRemoved = append(scanRemoved, removed...)
+		scanAdded = append(scanAdded, added...)
 	}
 
 	op.opSetStatus(opDone, err)
 
+	if len(scanRemoved) > 0 || len(scanAdded) > 0 {
+		go mediaScan(scanRemoved, scanAdded, op.su)
+	}
+
 	reloadpath := trimPath(dst, true)
 	if dstPane.getPath() == reloadpath {
 		dstPane.ChangeDir(false, false)
@@ -218,7 +265,7 @@ func transfermode(opmode opsMode, srcMode, dstMode ifaceMode) transferMode {
 	return localToLocal
 }
 
-func altPath(src, dst string, iface ifaceMode) (string, error) {
+func altPath(src, dst string, iface ifaceMode, su bool) (string, error) {
 	var try int
 	var existerr error
 
@@ -239,7 +286,7 @@ func altPath(src, dst string, iface ifaceMode) (string, error) {
 				return dst, err
 			}
 
-			_, existerr = adbStat(device, dst)
+			_, existerr = adbStat(device, dst, su)
 
 		case mLocal:
 			_, existerr = os.Lstat(dst)
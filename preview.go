@@ -0,0 +1,422 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/darkhz/tview"
+	adb "github.com/zach-klippenstein/goadb"
+)
+
+// previewRange reads up to n bytes of a file, starting at off.
+type previewRange func(off int64, n int) ([]byte, error)
+
+const (
+	defaultPreviewSize = 16
+	maxPreviewHex      = 4096
+	maxPreviewEntries  = 500
+	previewDelay       = 150 * time.Millisecond
+)
+
+var (
+	previewOn     bool
+	previewKey    string
+	previewCancel context.CancelFunc
+
+	previewView *tview.TextView
+	previewSep  *tview.Box
+	previewFlex *tview.Flex
+)
+
+func getPreviewSize() int {
+	if config.PreviewSize > 0 {
+		return config.PreviewSize * 1024
+	}
+
+	return defaultPreviewSize * 1024
+}
+
+// setupPreview adds the preview column to flex. It is hidden
+// unless the preview is enabled in the configuration.
+func setupPreview(flex *tview.Flex) {
+	previewFlex = flex
+
+	previewView = newTextView()
+	previewView.SetWrap(false)
+
+	previewSep = newVerticalSeparator()
+
+	flex.AddItem(previewSep, 0, 0, false)
+	flex.AddItem(previewView, 0, 0, false)
+
+	if config.Preview {
+		togglePreview()
+	}
+}
+
+func togglePreview() {
+	previewOn = !previewOn
+
+	if !previewOn {
+		previewFlex.ResizeItem(previewSep, 0, 0)
+		previewFlex.ResizeItem(previewView, 0, 0)
+
+		clearPreview()
+
+		return
+	}
+
+	previewFlex.ResizeItem(previewSep, 5, 0)
+	previewFlex.ResizeItem(previewView, 0, 1)
+
+	if prevPane != nil {
+		updatePreview(prevPane, true)
+	}
+}
+
+func clearPreview() {
+	if previewCancel != nil {
+		previewCancel()
+	}
+
+	previewKey = ""
+	previewView.Clear()
+}
+
+// updatePreview shows the highlighted entry of p in the preview column.
+// The entry is only read once the cursor has rested on it for a moment,
+// so that scrolling through a directory does not read every file.
+func updatePreview(p *dirPane, force bool) {
+	if !previewOn || p == nil {
+		return
+	}
+
+	row, _ := p.table.GetSelection()
+
+	cell := p.table.GetCell(row, 0)
+	if cell == nil {
+		return
+	}
+
+	entry, ok := cell.GetReference().(*adb.DirEntry)
+	if !ok || entry == nil || entry.Name == ".." {
+		clearPreview()
+		return
+	}
+
+	mode := p.mode
+	hidden := p.getHidden()
+	su := p.getSu()
+	path := filepath.Join(p.getPath(), entry.Name)
+
+	key := mode.String() + ":" + path
+	if key == previewKey && !force {
+		return
+	}
+
+	if previewCancel != nil {
+		previewCancel()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	previewKey = key
+	previewCancel = cancel
+
+	previewView.SetText(getStyle("info").tag() + tview.Escape(entry.Name) + "[-:-:-]\n\n" +
+		getStyle("column").tag() + "Loading...")
+	previewView.ScrollToBeginning()
+
+	go func() {
+		select {
+		case <-time.After(previewDelay):
+
+		case <-ctx.Done():
+			return
+		}
+
+		text := buildPreview(ctx, mode, path, entry, hidden, su)
+		if ctx.Err() != nil {
+			return
+		}
+
+		go app.QueueUpdateDraw(func() {
+			if previewKey == key {
+				previewView.SetText(text)
+				previewView.ScrollToBeginning()
+			}
+		})
+	}()
+}
+
+func buildPreview(ctx context.Context, mode ifaceMode, path string, entry *adb.DirEntry, hidden, su bool) string {
+	var size int64
+	var isdir bool
+
+	header := getStyle("info").tag() + tview.Escape(entry.Name) + "[-:-:-]\n"
+
+	switch mode {
+	case mAdb:
+		size = int64(uint32(entry.Size))
+		isdir = entry.Mode.IsDir()
+
+		if entry.Mode&os.ModeSymlink != 0 {
+			isdir = isAdbSymDir(filepath.Dir(path)+"/", filepath.Base(path), su)
+		}
+
+	case mLocal:
+		info, err := os.Stat(path)
+		if err != nil {
+			return header + "\n" + previewError(err)
+		}
+
+		size = info.Size()
+		isdir = info.IsDir()
+	}
+
+	if isdir {
+		return header + previewDir(mode, path, hidden, su)
+	}
+
+	rng := getPreviewRange(ctx, mode, path, su)
+
+	head, err := rng(0, getPreviewSize())
+	if err != nil && len(head) == 0 {
+		if size == 0 {
+			return header + previewField("Size", formatFileSize(0))
+		}
+
+		return header + "\n" + previewError(err)
+	}
+
+	header += previewField("Size", formatFileSize(size))
+	header += previewField("Type", getMimeType(path, head))
+
+	if fields, ok := getImageInfo(head, rng); ok {
+		return header + previewFields(fields)
+	}
+
+	if fields, ok := getMediaInfo(path, head, rng, size); ok {
+		return header + previewFields(fields)
+	}
+
+	truncated := int64(len(head)) < size
+
+	if text, ok := getPreviewText(head); ok {
+		text = highlightText(path, text)
+		if truncated {
+			text += "\n" + getStyle("column").tag() + "..."
+		}
+
+		return header + "\n" + text
+	}
+
+	dump := head
+	if len(dump) > maxPreviewHex {
+		dump = dump[:maxPreviewHex]
+		truncated = true
+	}
+
+	text := tview.Escape(hex.Dump(dump))
+	if truncated {
+		text += getStyle("column").tag() + "..."
+	}
+
+	return header + "\n" + text
+}
+
+func previewField(name, value string) string {
+	return getStyle("column").tag() + name + ":[-:-:-] " + tview.Escape(value) + "\n"
+}
+
+func previewFields(fields []fileInfoField) string {
+	text := "\n"
+
+	for _, f := range fields {
+		text += previewField(f.name, f.value)
+	}
+
+	return text
+}
+
+func previewError(err error) string {
+	return getStyle("error").tag() + tview.Escape(err.Error())
+}
+
+// previewDir lists the entries of a directory, directories first.
+func previewDir(mode ifaceMode, path string, hidden, su bool) string {
+	var entries []*adb.DirEntry
+
+	switch mode {
+	case mAdb:
+		device, err := getAdb()
+		if err != nil {
+			return "\n" + previewError(err)
+		}
+
+		entries, err = adbReadDir(device, path, su)
+		if err != nil {
+			return "\n" + previewError(err)
+		}
+
+	case mLocal:
+		list, err := os.ReadDir(path)
+		if err != nil {
+			return "\n" + previewError(err)
+		}
+
+		for _, d := range list {
+			info, err := d.Info()
+			if err != nil {
+				continue
+			}
+
+			entries = append(entries, &adb.DirEntry{
+				Name:       d.Name(),
+				Mode:       info.Mode(),
+				ModifiedAt: info.ModTime(),
+			})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Mode.IsDir() != entries[j].Mode.IsDir() {
+			return entries[i].Mode.IsDir()
+		}
+
+		return strings.ToLower(entries[i].Name) < strings.ToLower(entries[j].Name)
+	})
+
+	text := previewField("Entries", fmt.Sprintf("%d", len(entries))) + "\n"
+
+	var shown int
+
+	for _, entry := range entries {
+		if hidden && strings.HasPrefix(entry.Name, ".") {
+			continue
+		}
+
+		if shown >= maxPreviewEntries {
+			text += getStyle("column").tag() + "...\n"
+			break
+		}
+
+		name := entry.Name
+		if entry.Mode.IsDir() {
+			name += "/"
+		}
+
+		text += setEntryColor(0, false, getEntryPerms(entry), entry).tag() + tview.Escape(name) + "[-:-:-]\n"
+		shown++
+	}
+
+	return text
+}
+
+func getPreviewRange(ctx context.Context, mode ifaceMode, path string, su bool) previewRange {
+	if mode == mLocal {
+		return func(off int64, n int) ([]byte, error) {
+			f, err := os.Open(path)
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+
+			buf := make([]byte, n)
+
+			read, err := f.ReadAt(buf, off)
+			if err == io.EOF && read > 0 {
+				err = nil
+			}
+
+			return buf[:read], err
+		}
+	}
+
+	access := getShellAccess(path, su)
+
+	// Preview reads are deliberately not logged, as they
+	// occur every time the cursor rests on a file. In root
+	// mode, su is only used if the sync protocol cannot read it.
+	return func(off int64, n int) ([]byte, error) {
+		if off == 0 && (access == nil || access.su) {
+			device, err := getAdb()
+			if err != nil {
+				return nil, err
+			}
+
+			rd, err := device.OpenRead(path)
+			if err == nil {
+				defer rd.Close()
+
+				buf := make([]byte, n)
+
+				read, err := io.ReadFull(rd, buf)
+				if err == io.ErrUnexpectedEOF || (err == io.EOF && read == 0) {
+					err = nil
+				}
+
+				return buf[:read], err
+			}
+
+			if access == nil {
+				return nil, err
+			}
+		}
+
+		const bs = 4096
+
+		skip := off / bs
+		count := (off+int64(n)+bs-1)/bs - skip
+
+		cmd := fmt.Sprintf("dd if=%s bs=%d skip=%d count=%d 2>/dev/null", shellQuote(path), bs, skip, count)
+		cmd = wrapAdbCommand(path, cmd, su)
+
+		out, err := exec.CommandContext(ctx, "adb", "exec-out", cmd).Output()
+		if err != nil {
+			return nil, err
+		}
+
+		start := int(off - skip*bs)
+		if start >= len(out) {
+			return nil, io.EOF
+		}
+
+		out = out[start:]
+		if len(out) > n {
+			out = out[:n]
+		}
+
+		return out, nil
+	}
+}
+
+// getPreviewText returns data as text, if it looks like text.
+// A multi-byte character cut off at the end of data is dropped.
+func getPreviewText(data []byte) (string, bool) {
+	for i := 0; i < utf8.UTFMax && !utf8.Valid(data); i++ {
+		data = data[:len(data)-1]
+	}
+
+	if !utf8.Valid(data) {
+		return "", false
+	}
+
+	for _, r := range string(data) {
+		if r == 0 || (r < 0x20 && !unicode.IsSpace(r)) {
+			return "", false
+		}
+	}
+
+	return strings.ReplaceAll(string(data), "\t", "    "), true
+}
@@ -104,7 +104,7 @@ func (o *operation) setNewProgress(src, dst string, selindex, seltotal int) erro
 	tpath = "  " + opString(opstr) + " "
 
 	switch o.opmode {
-	case opDelete, opMkdir:
+	case opDelete, opMkdir, opInstall:
 		tpath += srcstr
 
 	case opCopy:
@@ -126,41 +126,39 @@ func (o *operation) setNewProgress(src, dst string, selindex, seltotal int) erro
 	o.updateOpsView(false, tpath, pstr)
 	addLog("setNewProgress", "updateOpsView returned", false)
 
-	if o.opmode != opRename || o.opmode != opMkdir {
-		if o.opmode == opCopy {
-			addLog("setNewProgress", "calling getTotalFiles", false)
-			err := o.getTotalFiles(src)
-			if err != nil {
-				return err
-			}
-			addLog("setNewProgress", fmt.Sprintf("getTotalFiles done: files=%d bytes=%d", o.totalFile, o.totalBytes), false)
+	if o.opmode == opCopy {
+		addLog("setNewProgress", "calling getTotalFiles", false)
+		err := o.getTotalFiles(src)
+		if err != nil {
+			return err
 		}
+		addLog("setNewProgress", fmt.Sprintf("getTotalFiles done: files=%d bytes=%d", o.totalFile, o.totalBytes), false)
+	}
 
-		addLog("setNewProgress", "calling createPb", false)
-		o.createPb()
-		addLog("setNewProgress", "createPb done", false)
+	addLog("setNewProgress", "calling createPb", false)
+	o.createPb()
+	addLog("setNewProgress", "createPb done", false)
 
-		if o.opmode != opCopy || o.transfer == adbToAdb {
-			go func() {
-				if !o.progress.lock.TryAcquire(1) {
-					return
-				}
-				defer o.progress.lock.Release(1)
+	if o.opmode != opCopy || o.transfer == adbToAdb {
+		go func() {
+			if !o.progress.lock.TryAcquire(1) {
+				return
+			}
+			defer o.progress.lock.Release(1)
 
-				for {
-					select {
-					case <-o.ctx.Done():
-						return
+			for {
+				select {
+				case <-o.ctx.Done():
+					return
 
-					default:
-					}
+				default:
+				}
 
-					o.progress.pbar.Add64(1)
+				o.progress.pbar.Add64(1)
 
-					time.Sleep(20 * time.Millisecond)
-				}
-			}()
-		}
+				time.Sleep(20 * time.Millisecond)
+			}
+		}()
 	}
 
 	return nil
@@ -8,7 +8,6 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
 	"syscall"
@@ -18,40 +17,15 @@ import (
 	adb "github.com/zach-klippenstein/goadb"
 )
 
-func (o *operation) sortEntries(list []*adb.DirEntry) {
-	sort.Slice(list, func(i, j int) bool {
-		var a, b int
-
-		if list[i].Mode.IsDir() != list[j].Mode.IsDir() {
-			return list[i].Mode.IsDir()
-		}
-
-		switch o.arrangeBy {
-		case "asc":
-			a, b = i, j
-
-		case "desc":
-			a, b = j, i
-		}
-
-		switch o.sortBy {
-		case "filetype":
-			if list[a].Mode.IsDir() || list[b].Mode.IsDir() {
-				break
-			}
-
-			return filepath.Ext(list[a].Name) < filepath.Ext(list[b].Name)
-
-		case "date":
-			return list[a].ModifiedAt.Unix() < list[b].ModifiedAt.Unix()
-		}
-
-		return list[a].Name < list[b].Name
-	})
+func (o *operation) sortEntries(list []*adb.DirEntry, sizes map[*adb.DirEntry]int64) {
+	sortDirEntries(list, o.sortMethod, sizes)
 }
 
 func (o *operation) pullFile(src, dst string, entry *adb.DirEntry, device *adb.Device, recursive bool) error {
-	remote, err := device.OpenRead(src)
+	var remote io.ReadCloser
+	var err error
+
+	remote, err = adbOpenRead(o.ctx, device, src, o.su)
 	if err != nil {
 		return err
 	}
@@ -100,7 +74,7 @@ func (o *operation) pullRecursive(src, dst string, device *adb.Device) error {
 		return fmt.Errorf("%s not implemented via pull", o.opmode.String())
 	}
 
-	stat, err := adbStat(device, src)
+	stat, err := adbStat(device, src, o.su)
 	if err != nil {
 		return err
 	}
@@ -119,21 +93,15 @@ func (o *operation) pullRecursive(src, dst string, device *adb.Device) error {
 		return err
 	}
 
-	listIter, err := adbListDirEntries(device, src)
-	var entries []*adb.DirEntry
-
-	for listIter.Next() {
-		entry := listIter.Entry()
-		entries = append(entries, entry)
-	}
-	if listIter.Err() != nil {
+	entries, err := adbListDirEntries(device, src, o.su)
+	if err != nil {
 		if isDir && logIndex >= 0 {
-			updateLog(logIndex, listIter.Err().Error(), true)
+			updateLog(logIndex, err.Error(), true)
 		}
-		return listIter.Err()
+		return err
 	}
 
-	o.sortEntries(entries)
+	o.sortEntries(entries, nil)
 
 	for _, entry := range entries {
 		s := filepath.Join(src, entry.Name)
@@ -174,6 +142,11 @@ func (o *operation) pushFile(src, dst string, entry os.FileInfo, device *adb.Dev
 		return nil
 	}
 
+	a := getShellAccess(dst, o.su)
+	if a != nil && !a.su {
+		return o.pushShellFile(a, src, dst)
+	}
+
 	// Use directory as destination, let adb figure out the filename
 	dstDir := filepath.Dir(dst)
 	logIndex := startLog(fmt.Sprintf("push %s %s (%.1f MB)", filepath.Base(src), dstDir, float64(entry.Size())/(1024*1024)))
@@ -190,6 +163,12 @@ func (o *operation) pushFile(src, dst string, entry os.FileInfo, device *adb.Dev
 		}
 		addLog("pushFile", fmt.Sprintf("adb push error: %v - %s", err, outStr), true)
 		updateLog(logIndex, errMsg, true)
+
+		// In root mode, retry with su if adb push cannot write to dst.
+		if a != nil && o.ctx.Err() == nil {
+			return o.pushShellFile(a, src, dst)
+		}
+
 		return fmt.Errorf("%s", errMsg)
 	}
 
@@ -203,6 +182,39 @@ func (o *operation) pushFile(src, dst string, entry os.FileInfo, device *adb.Dev
 	return nil
 }
 
+// pushShellFile streams a file to a path which adb push cannot write to.
+func (o *operation) pushShellFile(a *shellAccess, src, dst string) error {
+	logIndex := startLog(fmt.Sprintf("exec-in %s > %s", a.String(), dst))
+
+	local, err := os.Open(src)
+	if err != nil {
+		updateLog(logIndex, err.Error(), true)
+		return err
+	}
+	defer local.Close()
+
+	remote, err := a.openWrite(o.ctx, dst)
+	if err != nil {
+		updateLog(logIndex, err.Error(), true)
+		return err
+	}
+
+	_, err = io.Copy(remote, contextio.NewReader(o.ctx, local))
+	if cerr := remote.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		updateLog(logIndex, err.Error(), true)
+		return err
+	}
+
+	updateLog(logIndex, "success", false)
+	o.updatePb()
+
+	return nil
+}
+
 //gocyclo:ignore
 func (o *operation) pushRecursive(src, dst string, device *adb.Device) error {
 	addLog("pushRecursive", fmt.Sprintf("src=%s dst=%s", src, dst), false)
@@ -244,7 +256,7 @@ func (o *operation) pushRecursive(src, dst string, device *adb.Device) error {
 	defer srcfd.Close()
 
 	cmd := fmt.Sprintf("mkdir '%s'", dst)
-	out, err := runAdbShellCommand(device, cmd)
+	out, err := runAdbShellCommand(device, wrapAdbCommand(dst, cmd, o.su))
 
 	if err != nil {
 		return err
@@ -254,7 +266,7 @@ func (o *operation) pushRecursive(src, dst string, device *adb.Device) error {
 
 	mode := fmt.Sprintf("%04o", stat.Mode().Perm())
 	cmd = fmt.Sprintf("chmod %s '%s'", mode, dst)
-	out, err = runAdbShellCommand(device, cmd)
+	out, err = runAdbShellCommand(device, wrapAdbCommand(dst, cmd, o.su))
 
 	if err != nil {
 		return err
@@ -268,6 +280,7 @@ func (o *operation) pushRecursive(src, dst string, device *adb.Device) error {
 	}
 
 	var entries []*adb.DirEntry
+	sizes := make(map[*adb.DirEntry]int64, len(oslist))
 	for _, entry := range oslist {
 		var d adb.DirEntry
 		d.Name = entry.Name()
@@ -275,9 +288,10 @@ func (o *operation) pushRecursive(src, dst string, device *adb.Device) error {
 		d.Size = int32(entry.Size())
 		d.ModifiedAt = entry.ModTime()
 		entries = append(entries, &d)
+		sizes[&d] = entry.Size()
 	}
 
-	o.sortEntries(entries)
+	o.sortEntries(entries, sizes)
 
 	for _, entry := range entries {
 		s := filepath.Join(src, entry.Name)
@@ -378,6 +392,7 @@ func (o *operation) copyRecursive(src, dst string) error {
 	}
 
 	var entries []*adb.DirEntry
+	sizes := make(map[*adb.DirEntry]int64, len(oslist))
 	for _, entry := range oslist {
 		var d adb.DirEntry
 		d.Name = entry.Name()
@@ -385,9 +400,10 @@ func (o *operation) copyRecursive(src, dst string) error {
 		d.Size = int32(entry.Size())
 		d.ModifiedAt = entry.ModTime()
 		entries = append(entries, &d)
+		sizes[&d] = entry.Size()
 	}
 
-	o.sortEntries(entries)
+	o.sortEntries(entries, sizes)
 
 	for _, entry := range entries {
 		s := filepath.Join(src, entry.Name)
@@ -425,7 +441,7 @@ func (o *operation) getTotalFiles(src string) error {
 		}
 
 		cmd := fmt.Sprintf("find '%s' -type f | wc -l", src)
-		out, err := runAdbShellCommand(device, cmd)
+		out, err := runAdbShellCommand(device, wrapAdbCommand(src, cmd, o.su))
 
 		if err != nil {
 			return err
@@ -437,7 +453,7 @@ func (o *operation) getTotalFiles(src string) error {
 		}
 
 		cmd = fmt.Sprintf("du -d0 -sh '%s'", src)
-		out, err = runAdbShellCommand(device, cmd)
+		out, err = runAdbShellCommand(device, wrapAdbCommand(src, cmd, o.su))
 
 		if err != nil {
 			return err
@@ -0,0 +1,630 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/darkhz/tview"
+	"github.com/gdamore/tcell/v2"
+	adb "github.com/zach-klippenstein/goadb"
+)
+
+type searchQuery struct {
+	names    []string
+	regex    *regexp.Regexp
+	ftype    string
+	size     int64
+	sizeCmp  string
+	mtime    int
+	mtimeCmp string
+	hasSize  bool
+	hasMtime bool
+}
+
+type searchResult struct {
+	path  string
+	entry *adb.DirEntry
+}
+
+type search struct {
+	pane   *dirPane
+	mode   ifaceMode
+	root   string
+	text   string
+	query  searchQuery
+	hidden bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	count   int
+	status  string
+	pending []searchResult
+	lock    sync.Mutex
+
+	table *tview.Table
+	title *tview.TextView
+}
+
+const maxSearchResults = 10000
+
+// parseSearchQuery parses a search query of the form
+// "word re:regex type:f|d|l size:[+-]N[ckMG] mtime:[+-]days".
+// Words without wildcards match anywhere in the entry name.
+func parseSearchQuery(text string) (searchQuery, error) {
+	var q searchQuery
+	var err error
+
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return q, fmt.Errorf("Empty search query")
+	}
+
+	for _, field := range fields {
+		key, val, _ := strings.Cut(field, ":")
+
+		switch {
+		case key == "re" && val != "":
+			q.regex, err = regexp.Compile(val)
+
+		case key == "type" && val != "":
+			if val != "f" && val != "d" && val != "l" {
+				err = fmt.Errorf("Invalid type '%s' (use f, d or l)", val)
+			}
+
+			q.ftype = val
+
+		case key == "size" && val != "":
+			q.sizeCmp, val = parseSearchCmp(val)
+			q.size, err = parseSearchSize(val)
+			q.hasSize = true
+
+		case key == "mtime" && val != "":
+			q.mtimeCmp, val = parseSearchCmp(val)
+			q.mtime, err = strconv.Atoi(val)
+			q.hasMtime = true
+
+			if err != nil || q.mtime < 0 {
+				err = fmt.Errorf("Invalid mtime '%s'", field)
+			}
+
+		default:
+			if !strings.ContainsAny(field, "*?[") {
+				field = "*" + field + "*"
+			}
+
+			if _, err = filepath.Match(field, ""); err != nil {
+				err = fmt.Errorf("Invalid pattern '%s'", field)
+			}
+
+			q.names = append(q.names, field)
+		}
+
+		if err != nil {
+			return q, err
+		}
+	}
+
+	return q, nil
+}
+
+func parseSearchCmp(val string) (string, string) {
+	if strings.HasPrefix(val, "+") || strings.HasPrefix(val, "-") {
+		return val[:1], val[1:]
+	}
+
+	return "", val
+}
+
+func parseSearchSize(val string) (int64, error) {
+	var unit int64 = 1
+
+	if val != "" {
+		switch strings.ToLower(val[len(val)-1:]) {
+		case "c":
+			unit = 1
+
+		case "k":
+			unit = 1024
+
+		case "m":
+			unit = 1024 * 1024
+
+		case "g":
+			unit = 1024 * 1024 * 1024
+		}
+
+		if strings.ContainsAny(val[len(val)-1:], "cCkKmMgG") {
+			val = val[:len(val)-1]
+		}
+	}
+
+	size, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("Invalid size '%s'", val)
+	}
+
+	return size * unit, nil
+}
+
+func compareSearch(cmp string, value, target int64) bool {
+	switch cmp {
+	case "+":
+		return value > target
+
+	case "-":
+		return value < target
+	}
+
+	return value == target
+}
+
+// findCmd returns the device find command for the query. Regular
+// expressions are matched on the results, since the regex dialects
+// of the device find and Go differ.
+func (q searchQuery) findCmd(root string) string {
+	args := []string{"find", shellQuote(findPath(root)), "-mindepth", "1"}
+
+	for _, name := range q.names {
+		args = append(args, "-iname", shellQuote(name))
+	}
+
+	if q.hasSize {
+		args = append(args, "-size", q.sizeCmp+strconv.FormatInt(q.size, 10)+"c")
+	}
+
+	if q.hasMtime {
+		args = append(args, "-mtime", q.mtimeCmp+strconv.Itoa(q.mtime))
+	}
+
+	find := strings.Join(args, " ")
+
+	// Directories are marked with a trailing slash, so that
+	// their type is known without stat'ing every result.
+	switch q.ftype {
+	case "":
+		return fmt.Sprintf("%s -type d 2>/dev/null | sed 's|$|/|'; %s ! -type d 2>/dev/null", find, find)
+
+	case "d":
+		return fmt.Sprintf("%s -type d 2>/dev/null | sed 's|$|/|'", find)
+	}
+
+	return fmt.Sprintf("%s -type %s 2>/dev/null", find, q.ftype)
+}
+
+func (q searchQuery) matchName(name string) bool {
+	for _, pattern := range q.names {
+		if ok, _ := filepath.Match(strings.ToLower(pattern), strings.ToLower(name)); !ok {
+			return false
+		}
+	}
+
+	return q.regex == nil || q.regex.MatchString(name)
+}
+
+func (q searchQuery) matchInfo(info fs.FileInfo) bool {
+	switch q.ftype {
+	case "f":
+		if !info.Mode().IsRegular() {
+			return false
+		}
+
+	case "d":
+		if !info.IsDir() {
+			return false
+		}
+
+	case "l":
+		if info.Mode()&os.ModeSymlink == 0 {
+			return false
+		}
+	}
+
+	if q.hasSize && !compareSearch(q.sizeCmp, info.Size(), q.size) {
+		return false
+	}
+
+	if q.hasMtime {
+		days := int64(time.Since(info.ModTime()) / (24 * time.Hour))
+		if !compareSearch(q.mtimeCmp, days, int64(q.mtime)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p *dirPane) showSearchInput() {
+	input := getStatusInput("Search (name re: type: size: mtime:):", false)
+
+	exit := func() {
+		statuspgs.SwitchToPage("statusmsg")
+		app.SetFocus(p.table)
+	}
+
+	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEnter:
+			text := input.GetText()
+
+			query, err := parseSearchQuery(text)
+			if err != nil {
+				showErrorMsg(err, false)
+				return nil
+			}
+
+			exit()
+			p.startSearch(text, query)
+
+			return nil
+
+		case tcell.KeyEscape:
+			exit()
+			return nil
+		}
+
+		return event
+	})
+
+	statuspgs.AddAndSwitchToPage("searchinput", input, true)
+	app.SetFocus(input)
+}
+
+func (p *dirPane) startSearch(text string, query searchQuery) {
+	if p.mode == mAdb && !checkAdb() {
+		return
+	}
+
+	s := &search{
+		pane:   p,
+		mode:   p.mode,
+		root:   filepath.Clean(p.getPath()),
+		text:   text,
+		query:  query,
+		hidden: p.getHidden(),
+		status: "searching",
+		table:  tview.NewTable(),
+		title:  newTextView(),
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	s.setupView()
+
+	go s.run()
+}
+
+func (s *search) run() {
+	var err error
+
+	done := make(chan struct{})
+
+	go func() {
+		t := time.NewTicker(200 * time.Millisecond)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+
+			case <-t.C:
+				go app.QueueUpdateDraw(s.flush)
+			}
+		}
+	}()
+
+	switch s.mode {
+	case mAdb:
+		err = s.adbSearch()
+
+	case mLocal:
+		err = s.localSearch()
+	}
+
+	close(done)
+
+	s.lock.Lock()
+	switch {
+	case err != nil && s.ctx.Err() == nil:
+		s.status = "error: " + err.Error()
+
+	case s.count >= maxSearchResults:
+		s.status = fmt.Sprintf("stopped at %d results", maxSearchResults)
+
+	case s.ctx.Err() != nil:
+		s.status = "cancelled"
+
+	default:
+		s.status = "done"
+	}
+	s.lock.Unlock()
+
+	s.cancel()
+
+	go app.QueueUpdateDraw(s.flush)
+}
+
+func (s *search) adbSearch() error {
+	cmdtext := wrapAdbCommand(s.root, s.query.findCmd(s.root), s.pane.getSu())
+
+	logIndex := startLog(fmt.Sprintf("shell %s", cmdtext))
+
+	cmd := exec.CommandContext(s.ctx, "adb", "shell", cmdtext)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		updateLog(logIndex, err.Error(), true)
+		return err
+	}
+
+	if err = cmd.Start(); err != nil {
+		updateLog(logIndex, err.Error(), true)
+		return err
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		path := strings.TrimRight(scanner.Text(), "\r")
+		if path == "" {
+			continue
+		}
+
+		isdir := strings.HasSuffix(path, "/")
+
+		if path = filepath.Clean(path); !s.visible(path) {
+			continue
+		}
+
+		name := filepath.Base(path)
+		if s.query.regex != nil && !s.query.regex.MatchString(name) {
+			continue
+		}
+
+		entry := &adb.DirEntry{Name: name}
+		if isdir {
+			entry.Mode = os.ModeDir
+		}
+
+		if !s.add(searchResult{path, entry}) {
+			s.cancel()
+			break
+		}
+	}
+
+	// find exits with an error status for unreadable directories,
+	// which are common on the device, so only the result count is logged.
+	cmd.Wait()
+
+	updateLog(logIndex, fmt.Sprintf("%d results", s.count), false)
+
+	return nil
+}
+
+func (s *search) localSearch() error {
+	return filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if s.ctx.Err() != nil {
+			return filepath.SkipAll
+		}
+
+		if err != nil || path == s.root {
+			return nil
+		}
+
+		if !s.visible(path) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !s.query.matchName(d.Name()) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil || !s.query.matchInfo(info) {
+			return nil
+		}
+
+		entry := &adb.DirEntry{
+			Name:       d.Name(),
+			Mode:       info.Mode(),
+			ModifiedAt: info.ModTime(),
+		}
+
+		if !s.add(searchResult{path, entry}) {
+			return filepath.SkipAll
+		}
+
+		return nil
+	})
+}
+
+// visible reports whether path should be shown, according
+// to the hidden files setting of the searched pane.
+func (s *search) visible(path string) bool {
+	if !s.hidden {
+		return true
+	}
+
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil {
+		return true
+	}
+
+	for _, name := range strings.Split(filepath.ToSlash(rel), "/") {
+		if strings.HasPrefix(name, ".") && name != "." && name != ".." {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *search) add(r searchResult) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.count >= maxSearchResults {
+		return false
+	}
+
+	s.pending = append(s.pending, r)
+	s.count++
+
+	return true
+}
+
+// flush adds the results found since the last flush to the results view.
+func (s *search) flush() {
+	s.lock.Lock()
+	pending := s.pending
+	s.pending = nil
+	count, status := s.count, s.status
+	s.lock.Unlock()
+
+	row := s.table.GetRowCount()
+
+	for _, r := range pending {
+		s.table.SetCell(row, 0, s.resultCell(r))
+		row++
+	}
+
+	s.title.SetText(fmt.Sprintf(
+		"%sSearch '%s' in %s: %s: %d results (%s)",
+		getStyle("title").tag(),
+		tview.Escape(s.text),
+		s.mode.String(),
+		tview.Escape(s.root),
+		count,
+		tview.Escape(status),
+	))
+}
+
+func (s *search) resultCell(r searchResult) *tview.TableCell {
+	name, err := filepath.Rel(s.root, r.path)
+	if err != nil {
+		name = r.path
+	}
+
+	if r.entry.Mode.IsDir() {
+		name += "/"
+	}
+
+	perms := getEntryPerms(r.entry)
+
+	style := setEntryColor(0, checkmsel(r.path), perms, r.entry)
+
+	cell := tview.NewTableCell(tview.Escape(name))
+	cell.SetReference(r)
+	cell.SetStyle(style.style())
+	cell.SetSelectedStyle(getStyle("cursor").style())
+
+	return cell
+}
+
+func (s *search) toggle(row int) {
+	cell := s.table.GetCell(row, 0)
+	if cell == nil || cell.GetReference() == nil {
+		return
+	}
+
+	r := cell.GetReference().(searchResult)
+
+	if checkmsel(r.path) {
+		delmsel(r.path)
+	} else {
+		addmsel(r.path, s.mode)
+		selected = true
+	}
+
+	s.table.SetCell(row, 0, s.resultCell(r))
+}
+
+func (s *search) setupView() {
+	flex := tview.NewFlex().
+		AddItem(s.title, 1, 0, false).
+		AddItem(s.table, 0, 1, true).
+		SetDirection(tview.FlexRow)
+
+	exit := func() {
+		s.cancel()
+
+		pages.SwitchToPage("main")
+		pages.RemovePage("search")
+
+		app.SetFocus(s.pane.table)
+
+		t := tabs[tabPos]
+		t.selPane.reselect(false)
+		t.auxPane.reselect(false)
+	}
+
+	s.table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch getKeyAction(kSearch, event) {
+		case "search-goto":
+			row, _ := s.table.GetSelection()
+
+			cell := s.table.GetCell(row, 0)
+			if cell == nil || cell.GetReference() == nil {
+				return nil
+			}
+
+			r := cell.GetReference().(searchResult)
+
+			exit()
+			s.pane.jumpToEntry(s.mode, filepath.Dir(r.path), filepath.Base(r.path))
+
+			return nil
+
+		case "search-select":
+			row, _ := s.table.GetSelection()
+			s.toggle(row)
+
+			if row+1 < s.table.GetRowCount() {
+				s.table.Select(row+1, 0)
+			}
+
+			return nil
+
+		case "search-select-all":
+			for row := 0; row < s.table.GetRowCount(); row++ {
+				if r, ok := s.table.GetCell(row, 0).GetReference().(searchResult); ok && !checkmsel(r.path) {
+					s.toggle(row)
+				}
+			}
+
+			return nil
+
+		case "search-cancel":
+			s.cancel()
+			return nil
+
+		case "search-exit":
+			exit()
+			return nil
+		}
+
+		return event
+	})
+
+	s.table.SetSelectable(true, false)
+	s.table.SetBackgroundColor(tcell.ColorDefault)
+
+	s.flush()
+
+	pages.AddAndSwitchToPage("search", flex, true)
+	app.SetFocus(s.table)
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type paneSession struct {
+	Mode        string    `json:"mode"`
+	Path        string    `json:"path"`
+	APath       string    `json:"adb_path"`
+	DPath       string    `json:"local_path"`
+	Hidden      bool      `json:"hidden"`
+	Sort        sortState `json:"sort"`
+	History     []string  `json:"history"`
+	HistoryMode []string  `json:"history_mode"`
+	HistoryPos  int       `json:"history_pos"`
+}
+
+type selectionSession struct {
+	Path string `json:"path"`
+	Mode string `json:"mode"`
+}
+
+type tabSession struct {
+	Name  string        `json:"name,omitempty"`
+	Panes []paneSession `json:"panes"`
+}
+
+type session struct {
+	Serial     string             `json:"serial"`
+	Tabs       []tabSession       `json:"tabs"`
+	Tab        int                `json:"tab"`
+	Selections []selectionSession `json:"selections"`
+}
+
+var lastSession *session
+
+func loadSession() (*session, error) {
+	var s session
+
+	spath := configPath("session.json")
+	if spath == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(spath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%s: %s", spath, err.Error())
+	}
+
+	return &s, nil
+}
+
+func saveSession() error {
+	spath := configPath("session.json")
+	if spath == "" {
+		return nil
+	}
+
+	s := session{
+		Serial: deviceSerial,
+		Tab:    tabPos,
+	}
+
+	for _, t := range tabs {
+		s.Tabs = append(s.Tabs, tabSession{
+			Name: t.name,
+			Panes: []paneSession{
+				t.selPane.getSession(),
+				t.auxPane.getSession(),
+			},
+		})
+	}
+
+	for _, sel := range getselection() {
+		s.Selections = append(s.Selections, selectionSession{
+			Path: sel.path,
+			Mode: sel.smode.String(),
+		})
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeConfigFile(spath, data)
+}
+
+func (p *dirPane) getSession() paneSession {
+	ps := paneSession{
+		Mode:       p.mode.String(),
+		Path:       filepath.Clean(p.getPath()),
+		APath:      p.apath,
+		DPath:      p.dpath,
+		Hidden:     p.getHidden(),
+		Sort:       p.getSortMethod().state(),
+		History:    p.history,
+		HistoryPos: p.historyPos,
+	}
+
+	for _, mode := range p.historyMode {
+		ps.HistoryMode = append(ps.HistoryMode, mode.String())
+	}
+
+	return ps
+}
+
+// restore recreates the tabs of a saved session. Device paths are
+// skipped if the device the session was saved with is not connected.
+func (s *session) restore() {
+	device := s.Serial != "" && s.Serial == getDeviceSerial()
+
+	for _, sel := range s.Selections {
+		mode, err := parseIfaceMode(sel.Mode)
+		if err != nil || (mode == mAdb && !device) {
+			continue
+		}
+
+		addmsel(sel.Path, mode)
+		selected = true
+	}
+
+	for _, ts := range s.Tabs {
+		selPane := newDirPane(initSelMode, initSelPath)
+		auxPane := newDirPane(initAuxMode, initAuxPath)
+
+		for i, pane := range []*dirPane{selPane, auxPane} {
+			if i >= len(ts.Panes) {
+				break
+			}
+
+			ts.Panes[i].apply(pane, device)
+		}
+
+		newTab(ts.Name, selPane, auxPane)
+	}
+
+	if len(tabs) > 0 {
+		if s.Tab < 0 || s.Tab >= len(tabs) {
+			s.Tab = 0
+		}
+
+		switchTab(s.Tab)
+	}
+}
+
+func (ps paneSession) apply(p *dirPane, device bool) {
+	mode, err := parseIfaceMode(ps.Mode)
+	if err != nil {
+		return
+	}
+
+	if device && ps.APath != "" && checkRestorePath(mAdb, ps.APath) {
+		p.apath = ps.APath
+	}
+
+	if ps.DPath != "" && checkRestorePath(mLocal, ps.DPath) {
+		p.dpath = ps.DPath
+	}
+
+	switch {
+	case mode == mAdb && !device:
+		// Keep the pane's initial mode and path.
+
+	case checkRestorePath(mode, ps.Path):
+		p.mode = mode
+		p.path = ps.Path
+
+	case mode == mAdb:
+		p.mode = mode
+		p.path = p.apath
+
+	default:
+		p.mode = mode
+		p.path = p.dpath
+	}
+
+	p.hidden = ps.Hidden
+	p.sortMethod = ps.Sort.sortData()
+
+	pos := ps.HistoryPos
+	posDropped := false
+
+	for i, hpath := range ps.History {
+		if i >= len(ps.HistoryMode) {
+			break
+		}
+
+		hmode, err := parseIfaceMode(ps.HistoryMode[i])
+		if err != nil || (hmode == mAdb && !device) {
+			switch {
+			case i < ps.HistoryPos:
+				pos--
+
+			case i == ps.HistoryPos:
+				posDropped = true
+			}
+
+			continue
+		}
+
+		p.history = append(p.history, hpath)
+		p.historyMode = append(p.historyMode, hmode)
+	}
+
+	// If the current entry was dropped, fall back to the surviving
+	// entry before it, or the one after it if there is none.
+	if posDropped && pos > 0 {
+		pos--
+	}
+
+	p.historyPos = pos
+	if p.historyPos < 0 || p.historyPos >= len(p.history) {
+		p.historyPos = len(p.history) - 1
+	}
+}
+
+func checkRestorePath(mode ifaceMode, path string) bool {
+	switch mode {
+	case mAdb:
+		device, err := getAdb()
+		if err != nil {
+			return false
+		}
+
+		stat, err := adbStat(device, path, false)
+
+		return err == nil && stat.Mode.IsDir()
+
+	case mLocal:
+		stat, err := os.Stat(path)
+
+		return err == nil && stat.IsDir()
+	}
+
+	return false
+}
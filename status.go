@@ -97,6 +97,8 @@ func setupStatus() {
 	statuspgs = tview.NewPages()
 
 	statusmsg = newTextView()
+	statusmsg.SetTextColor(getStyle("status").fg)
+	statusmsg.SetBackgroundColor(getStyle("status").bg)
 
 	statuspgs.AddPage("statusmsg", statusmsg, true, true)
 
@@ -126,7 +128,7 @@ func getStatusInput(msg string, accept bool) *tview.InputField {
 }
 
 func showInfoMsg(msg string) {
-	sendMessage(message{"[::b]" + tview.Escape(msg), false})
+	sendMessage(message{getStyle("info").tag() + tview.Escape(msg), false})
 }
 
 func showErrorMsg(err error, autocomplete bool) {
@@ -134,7 +136,7 @@ func showErrorMsg(err error, autocomplete bool) {
 		return
 	}
 
-	sendMessage(message{"[red::b]" + tview.Escape(err.Error()), false})
+	sendMessage(message{getStyle("error").tag() + tview.Escape(err.Error()), false})
 }
 
 func showConfirmMsg(msg string, defaultChoice string, doFunc, resetFunc func()) {
@@ -236,9 +238,10 @@ func showConfirmMsg(msg string, defaultChoice string, doFunc, resetFunc func())
 }
 
 func (p *dirPane) showFilterInput() {
-	var regex bool
 	var skipCallback bool
 
+	regex := p.fregex
+
 	input := getStatusInput("", false)
 
 	inputlabel := func() {
@@ -256,6 +259,9 @@ func (p *dirPane) showFilterInput() {
 
 	exit := func() {
 		p.finput = input.GetText()
+		p.fregex = regex
+		p.saveViewState()
+
 		statuspgs.SwitchToPage("statusmsg")
 		app.SetFocus(prevPane.table)
 	}
@@ -296,23 +302,14 @@ func (p *dirPane) showFilterInput() {
 				}
 			} else {
 				// Filter mode
+				match, err := getFilterMatcher(text, regex)
+				if err != nil {
+					return
+				}
+
 				p.filter = true
 				for _, dir := range p.pathList {
-					match := false
-					if regex {
-						re, err := regexp.Compile(text)
-						if err != nil {
-							return
-						}
-						match = re.Match([]byte(dir.Name))
-					} else {
-						match = strings.Contains(
-							strings.ToLower(dir.Name),
-							strings.ToLower(text),
-						)
-					}
-
-					if match {
+					if match(dir.Name) {
 						sel := checkSelected(p.path, dir.Name, false)
 						filtered = append(filtered, filteredEntry{len(filtered), dir, sel})
 					}
@@ -331,14 +328,16 @@ func (p *dirPane) showFilterInput() {
 	})
 
 	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyCtrlR:
+		switch getKeyAction(kFilter, event) {
+		case "filter-clear":
 			go p.reselect(true)
 
-		case tcell.KeyCtrlF:
+		case "filter-regex":
 			regex = !regex
 			inputlabel()
+		}
 
+		switch event.Key() {
 		case tcell.KeyUp, tcell.KeyDown:
 			p.table.InputHandler()(event, nil)
 			// Must be async so input handler can return before UI operations
@@ -364,6 +363,23 @@ func (p *dirPane) showFilterInput() {
 	skipCallback = false
 }
 
+func getFilterMatcher(text string, regex bool) (func(name string) bool, error) {
+	if regex {
+		re, err := regexp.Compile(text)
+		if err != nil {
+			return nil, err
+		}
+
+		return re.MatchString, nil
+	}
+
+	text = strings.ToLower(text)
+
+	return func(name string) bool {
+		return strings.Contains(strings.ToLower(name), text)
+	}, nil
+}
+
 func showMkdirRenameInput(selPane, auxPane *dirPane, key rune) {
 	var title string
 	var rename bool
@@ -443,58 +459,75 @@ func showMkdirRenameInput(selPane, auxPane *dirPane, key rune) {
 func (p *dirPane) showSortDirInput() {
 	input := getStatusInput("", true)
 
-	sortmethods := []string{
-		"asc",
-		"desc",
-		"filetype",
-		"date",
-		"name",
+	sortmethods := []struct {
+		key   rune
+		name  string
+		label string
+	}{
+		{'a', "asc", "(a)sc"},
+		{'d', "desc", "(d)esc"},
+		{'f', "filetype", "(f)iletype"},
+		{'t', "date", "da(t)e"},
+		{'n', "name", "(n)ame"},
+		{'s', "size", "(s)ize"},
+		{'u', "natural", "nat(u)ral"},
+		{'c', "ignorecase", "(c)ase-insensitive"},
+		{'m', "mixed", "(m)ix dirs"},
 	}
 
 	inputlabel := func() {
 		label := "[::b]Sort by: "
-		sortmethod, arrange := p.getSortMethod()
+		method := p.getSortMethod()
 
 		for _, st := range sortmethods {
-			if st == sortmethod || st == arrange {
-				label += "*"
+			var active bool
+
+			switch st.name {
+			case "natural":
+				label += "| "
+				active = method.natural
+
+			case "ignorecase":
+				active = method.ignoreCase
+
+			case "mixed":
+				active = method.mixed
+
+			default:
+				active = st.name == method.sortBy || st.name == method.arrangeBy
 			}
 
-			if st == "date" {
-				st = "da(t)e"
-			} else {
-				st = "(" + string(st[0]) + ")" + string(st[1:])
+			if active {
+				label += "*"
 			}
 
-			label += st + " "
+			label += st.label + " "
 		}
 
 		input.SetLabel(label)
 	}
 
 	setsort := func(t rune) {
-		var s, a string
-
-		switch t {
-		case 'a':
-			a = sortmethods[0]
-
-		case 'd':
-			a = sortmethods[1]
+		for _, st := range sortmethods {
+			if st.key != t {
+				continue
+			}
 
-		case 'f':
-			s = sortmethods[2]
+			switch st.name {
+			case "asc", "desc":
+				p.setSortMethod("", st.name)
 
-		case 't':
-			s = sortmethods[3]
+			case "natural", "ignorecase", "mixed":
+				p.toggleSortOption(st.name)
 
-		case 'n':
-			s = sortmethods[4]
+			default:
+				p.setSortMethod(st.name, "")
+			}
 		}
 
-		p.setSortMethod(s, a)
 		inputlabel()
 
+		p.saveViewState()
 		p.ChangeDir(false, false)
 	}
 
@@ -502,7 +535,7 @@ func (p *dirPane) showSortDirInput() {
 
 	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		switch event.Rune() {
-		case 'a', 'd', 'f', 't', 'n':
+		case 'a', 'd', 'f', 't', 'n', 's', 'u', 'c', 'm':
 			setsort(event.Rune())
 			return nil
 		}
@@ -568,8 +601,8 @@ func execCommand() {
 	}
 
 	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyCtrlA:
+		switch getKeyAction(kExec, event) {
+		case "exec-iface":
 			if imode == "Local" {
 				imode = "Adb"
 			} else {
@@ -578,7 +611,7 @@ func execCommand() {
 
 			inputlabel()
 
-		case tcell.KeyCtrlQ:
+		case "exec-mode":
 			if emode == "Foreground" {
 				emode = "Background"
 			} else {
@@ -586,7 +619,9 @@ func execCommand() {
 			}
 
 			inputlabel()
+		}
 
+		switch event.Key() {
 		case tcell.KeyEnter:
 			cmdexec(input.GetText())
 			fallthrough
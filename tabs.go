@@ -0,0 +1,279 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/darkhz/tview"
+	"github.com/gdamore/tcell/v2"
+)
+
+type tab struct {
+	id       string
+	name     string
+	selPane  *dirPane
+	auxPane  *dirPane
+	prevPane *dirPane
+	view     *tview.Flex
+	title    *tview.Flex
+}
+
+var (
+	tabs   []*tab
+	tabPos int
+	tabID  int
+
+	tabBar     *tview.TextView
+	tabPages   *tview.Pages
+	titlePages *tview.Pages
+)
+
+// newTab creates a tab holding the provided pane pair, and loads
+// the directories of both panes.
+func newTab(name string, selPane, auxPane *dirPane) *tab {
+	tabID++
+
+	t := &tab{
+		id:       "tab" + strconv.Itoa(tabID),
+		name:     name,
+		selPane:  selPane,
+		auxPane:  auxPane,
+		prevPane: selPane,
+	}
+
+	selPane.focused = true
+	auxPane.focused = false
+
+	setupPane(selPane, auxPane, true)
+	setupPane(auxPane, selPane, true)
+
+	t.view = tview.NewFlex().
+		AddItem(selPane.table, 0, 1, true).
+		AddItem(newVerticalSeparator(), 5, 0, false).
+		AddItem(auxPane.table, 0, 1, false).
+		SetDirection(tview.FlexColumn)
+
+	t.title = tview.NewFlex().
+		AddItem(selPane.title, 0, 1, false).
+		AddItem(tview.NewBox().SetBackgroundColor(tcell.ColorDefault), 1, 0, false).
+		AddItem(auxPane.title, 0, 1, false)
+
+	tabs = append(tabs, t)
+
+	tabPages.AddPage(t.id, t.view, true, false)
+	titlePages.AddPage(t.id, t.title, true, false)
+
+	return t
+}
+
+// newTabFrom creates a tab whose panes start at the
+// locations and settings of the current tab's panes.
+func newTabFrom(cur *tab) {
+	clone := func(p *dirPane) *dirPane {
+		pane := newDirPane(p.mode, p.getPath())
+
+		pane.apath = p.apath
+		pane.dpath = p.dpath
+		pane.hidden = p.getHidden()
+		pane.sortMethod = p.getSortMethod()
+
+		return pane
+	}
+
+	newTab("", clone(cur.selPane), clone(cur.auxPane))
+	switchTab(len(tabs) - 1)
+}
+
+func switchTab(pos int) {
+	if pos < 0 || pos >= len(tabs) {
+		return
+	}
+
+	if tabPos < len(tabs) && prevPane != nil {
+		tabs[tabPos].prevPane = prevPane
+	}
+
+	tabPos = pos
+	t := tabs[tabPos]
+
+	prevPane = t.prevPane
+
+	tabPages.SwitchToPage(t.id)
+	titlePages.SwitchToPage(t.id)
+
+	updateTabBar()
+
+	if app != nil {
+		app.SetFocus(prevPane.table)
+		updatePreview(prevPane, false)
+	}
+}
+
+func cycleTab(next bool) {
+	if len(tabs) < 2 {
+		showInfoMsg("No other tabs")
+		return
+	}
+
+	pos := tabPos - 1
+	if next {
+		pos = tabPos + 1
+	}
+
+	switchTab((pos + len(tabs)) % len(tabs))
+}
+
+func closeTab() {
+	if len(tabs) < 2 {
+		showInfoMsg("Cannot close the last tab")
+		return
+	}
+
+	t := tabs[tabPos]
+
+	for _, pane := range []*dirPane{t.selPane, t.auxPane} {
+		pane.saveViewState()
+	}
+
+	tabs = append(tabs[:tabPos], tabs[tabPos+1:]...)
+
+	tabPages.RemovePage(t.id)
+	titlePages.RemovePage(t.id)
+
+	pos := tabPos
+	if pos >= len(tabs) {
+		pos = len(tabs) - 1
+	}
+
+	prevPane = nil
+	switchTab(pos)
+
+	showInfoMsg("Closed tab '" + t.getName() + "'")
+}
+
+func showRenameTabInput() {
+	t := tabs[tabPos]
+
+	input := getStatusInput("Tab name:", false)
+	input.SetText(t.name)
+
+	exit := func() {
+		statuspgs.SwitchToPage("statusmsg")
+		app.SetFocus(prevPane.table)
+	}
+
+	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEnter:
+			t.name = strings.TrimSpace(input.GetText())
+			updateTabBar()
+
+			fallthrough
+
+		case tcell.KeyEscape:
+			exit()
+			return nil
+		}
+
+		return event
+	})
+
+	statuspgs.AddAndSwitchToPage("tabinput", input, true)
+	app.SetFocus(input)
+}
+
+// getName returns the name of the tab. Unnamed tabs are
+// named after the directory of the tab's focused pane.
+func (t *tab) getName() string {
+	if t.name != "" {
+		return t.name
+	}
+
+	pane := t.prevPane
+	if pane == nil {
+		pane = t.selPane
+	}
+
+	name := filepath.Base(pane.getPath())
+	if name == "." || name == "" {
+		name = "/"
+	}
+
+	return name
+}
+
+// updateTabBar lists the tabs before the pane titles. The
+// tab bar is hidden if there is only one tab.
+func updateTabBar() {
+	if tabBar == nil || titleBar == nil {
+		return
+	}
+
+	if len(tabs) < 2 {
+		tabBar.SetText("")
+		titleBar.ResizeItem(tabBar, 0, 0)
+
+		return
+	}
+
+	var text string
+
+	for i, t := range tabs {
+		name := fmt.Sprintf(" %d:%s ", i+1, tview.Escape(trimName(t.getName(), 20, false)))
+
+		if i == tabPos {
+			name = getStyle("title").tag() + "[::r]" + name + "[-:-:-]"
+		}
+
+		text += name
+	}
+
+	tabBar.SetText(text)
+	titleBar.ResizeItem(tabBar, tview.TaggedStringWidth(text)+1, 0)
+}
+
+// getAuxPane returns the other pane of the tab holding p.
+func getAuxPane(p *dirPane) *dirPane {
+	for _, t := range tabs {
+		switch p {
+		case t.selPane:
+			return t.auxPane
+
+		case t.auxPane:
+			return t.selPane
+		}
+	}
+
+	return p
+}
+
+func allPanes() []*dirPane {
+	var panes []*dirPane
+
+	for _, t := range tabs {
+		panes = append(panes, t.selPane, t.auxPane)
+	}
+
+	return panes
+}
+
+func newVerticalSeparator() *tview.Box {
+	return tview.NewBox().
+		SetBackgroundColor(tcell.ColorDefault).
+		SetDrawFunc(func(screen tcell.Screen, x, y, width, height int) (int, int, int, int) {
+			centerX := x + width/2
+			for cy := y; cy < y+height; cy++ {
+				screen.SetContent(
+					centerX,
+					cy,
+					tview.BoxDrawingsLightVertical,
+					nil,
+					tcell.StyleDefault.Foreground(tcell.ColorDefault),
+				)
+			}
+
+			return x + 1, centerX + 1, width - 2, height - (centerX + 1 - y)
+		})
+}
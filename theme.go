@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+type themeStyle struct {
+	fg   tcell.Color
+	bg   tcell.Color
+	attr tcell.AttrMask
+}
+
+var themeStyleNames = []string{
+	"file",
+	"directory",
+	"symlink",
+	"executable",
+	"socket",
+	"device",
+	"special",
+	"column",
+	"selected",
+	"cursor",
+	"title",
+	"status",
+	"info",
+	"error",
+	"logcommand",
+	"logerror",
+	"progress",
+	"marked",
+	"unmarked",
+	"keyword",
+	"string",
+	"comment",
+	"number",
+}
+
+var builtinThemes = map[string]map[string]string{
+	"default": {
+		"file":       "",
+		"directory":  "navy::b",
+		"symlink":    "teal::b",
+		"executable": "green",
+		"socket":     "purple::b",
+		"device":     "olive::b",
+		"special":    "maroon::b",
+		"column":     "::d",
+		"selected":   "orange::b",
+		"cursor":     "::r",
+		"title":      "::bu",
+		"status":     "",
+		"info":       "::b",
+		"error":      "red::b",
+		"logcommand": "::b",
+		"logerror":   "red::b",
+		"progress":   "",
+		"marked":     "green",
+		"unmarked":   "red",
+		"keyword":    "purple::b",
+		"string":     "green",
+		"comment":    "::d",
+		"number":     "teal",
+	},
+	"light": {
+		"file":       "black",
+		"directory":  "blue::b",
+		"symlink":    "darkcyan::b",
+		"executable": "darkgreen::b",
+		"socket":     "darkmagenta::b",
+		"device":     "saddlebrown::b",
+		"special":    "darkred::b",
+		"column":     "dimgray",
+		"selected":   "darkorange::bu",
+		"cursor":     "::r",
+		"title":      "black::bu",
+		"status":     "black",
+		"info":       "black::b",
+		"error":      "darkred::b",
+		"logcommand": "black::b",
+		"logerror":   "darkred::b",
+		"progress":   "black",
+		"marked":     "darkgreen::b",
+		"unmarked":   "darkred",
+		"keyword":    "darkmagenta::b",
+		"string":     "darkgreen",
+		"comment":    "dimgray",
+		"number":     "darkcyan",
+	},
+	"mono": {
+		"file":       "",
+		"directory":  "::b",
+		"symlink":    "::u",
+		"executable": "::i",
+		"socket":     "::bi",
+		"device":     "::bi",
+		"special":    "::bu",
+		"column":     "::d",
+		"selected":   "::bu",
+		"cursor":     "::r",
+		"title":      "::bu",
+		"status":     "",
+		"info":       "::b",
+		"error":      "::bu",
+		"logcommand": "::b",
+		"logerror":   "::bu",
+		"progress":   "",
+		"marked":     "::b",
+		"unmarked":   "::d",
+		"keyword":    "::b",
+		"string":     "::i",
+		"comment":    "::d",
+		"number":     "",
+	},
+}
+
+var theme map[string]themeStyle
+
+func init() {
+	theme, _ = newTheme(builtinThemes["default"], nil)
+}
+
+func loadTheme(name string) error {
+	var base string
+	var styles map[string]string
+
+	if name == "" {
+		return nil
+	}
+
+	if _, ok := builtinThemes[name]; ok {
+		base = name
+	} else {
+		tpath := name
+		if !strings.ContainsRune(tpath, filepath.Separator) {
+			tpath = configPath(filepath.Join("themes", name+".json"))
+		}
+
+		data, err := os.ReadFile(tpath)
+		if err != nil {
+			return fmt.Errorf("theme '%s': %s", name, err.Error())
+		}
+
+		if err = json.Unmarshal(data, &styles); err != nil {
+			return fmt.Errorf("theme '%s': %s", name, err.Error())
+		}
+
+		base = styles["base"]
+		delete(styles, "base")
+
+		if base == "" {
+			base = "default"
+		}
+	}
+
+	baseStyles, ok := builtinThemes[base]
+	if !ok {
+		return fmt.Errorf("theme '%s': unknown base theme '%s'", name, base)
+	}
+
+	t, err := newTheme(baseStyles, styles)
+	if err != nil {
+		return fmt.Errorf("theme '%s': %s", name, err.Error())
+	}
+
+	theme = t
+
+	return nil
+}
+
+func newTheme(base, overrides map[string]string) (map[string]themeStyle, error) {
+	t := make(map[string]themeStyle, len(themeStyleNames))
+
+	for _, name := range themeStyleNames {
+		spec, ok := overrides[name]
+		if !ok {
+			spec = base[name]
+		}
+
+		style, err := parseThemeStyle(spec)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err.Error())
+		}
+
+		t[name] = style
+	}
+
+	for name := range overrides {
+		if _, ok := t[name]; !ok {
+			return nil, fmt.Errorf("unknown style '%s'", name)
+		}
+	}
+
+	return t, nil
+}
+
+func parseThemeStyle(spec string) (themeStyle, error) {
+	style := themeStyle{
+		fg: tcell.ColorDefault,
+		bg: tcell.ColorDefault,
+	}
+
+	fields := strings.Split(spec, ":")
+	if len(fields) > 3 {
+		return style, fmt.Errorf("invalid style '%s'", spec)
+	}
+
+	for i, field := range fields {
+		switch i {
+		case 0, 1:
+			color, err := parseThemeColor(field)
+			if err != nil {
+				return style, err
+			}
+
+			if i == 0 {
+				style.fg = color
+			} else {
+				style.bg = color
+			}
+
+		case 2:
+			for _, a := range field {
+				switch a {
+				case 'b':
+					style.attr |= tcell.AttrBold
+				case 'd':
+					style.attr |= tcell.AttrDim
+				case 'i':
+					style.attr |= tcell.AttrItalic
+				case 'l':
+					style.attr |= tcell.AttrBlink
+				case 'r':
+					style.attr |= tcell.AttrReverse
+				case 'u':
+					style.attr |= tcell.AttrUnderline
+				default:
+					return style, fmt.Errorf("invalid attribute '%c'", a)
+				}
+			}
+		}
+	}
+
+	return style, nil
+}
+
+func parseThemeColor(name string) (tcell.Color, error) {
+	switch name {
+	case "", "-", "default":
+		return tcell.ColorDefault, nil
+	}
+
+	color := tcell.GetColor(name)
+	if color == tcell.ColorDefault {
+		return color, fmt.Errorf("invalid color '%s'", name)
+	}
+
+	return color, nil
+}
+
+func getStyle(name string) themeStyle {
+	return theme[name]
+}
+
+func (s themeStyle) style() tcell.Style {
+	return tcell.StyleDefault.
+		Foreground(s.fg).
+		Background(s.bg).
+		Attributes(s.attr)
+}
+
+// tag returns the style as a tview color tag, for use in dynamic color text.
+func (s themeStyle) tag() string {
+	var attrs string
+
+	for _, a := range []struct {
+		mask tcell.AttrMask
+		ch   string
+	}{
+		{tcell.AttrBold, "b"},
+		{tcell.AttrDim, "d"},
+		{tcell.AttrItalic, "i"},
+		{tcell.AttrBlink, "l"},
+		{tcell.AttrReverse, "r"},
+		{tcell.AttrUnderline, "u"},
+	} {
+		if s.attr&a.mask != 0 {
+			attrs += a.ch
+		}
+	}
+
+	if attrs == "" {
+		attrs = "-"
+	}
+
+	return "[" + colorTag(s.fg) + ":" + colorTag(s.bg) + ":" + attrs + "]"
+}
+
+func colorTag(color tcell.Color) string {
+	if color == tcell.ColorDefault {
+		return "-"
+	}
+
+	for name, c := range tcell.ColorNames {
+		if c == color {
+			return name
+		}
+	}
+
+	return fmt.Sprintf("#%06x", color.Hex())
+}
@@ -3,7 +3,6 @@ package main
 import (
 	"os"
 	"path/filepath"
-	"strings"
 	"syscall"
 
 	"github.com/darkhz/tview"
@@ -18,6 +17,7 @@ type dirPane struct {
 	apath       string
 	dpath       string
 	finput      string
+	fregex      bool
 	filter      bool
 	hidden      bool
 	focused     bool
@@ -26,54 +26,47 @@ type dirPane struct {
 	plock       *semaphore.Weighted
 	entry       *adb.DirEntry
 	pathList    []*adb.DirEntry
+	sizes       map[*adb.DirEntry]int64
 	title       *tview.TextView
 	sortMethod  sortData
 	history     []string
 	historyPos  int
 	historyMode []ifaceMode
+	vkey        string
+	vcursor     string
+	vrestore    bool
+	jump        string
+	free        int64
+	su          bool
 }
 
 var (
 	app      *tview.Application
 	pages    *tview.Pages
 	opsView  *tview.Table
-	selPane  *dirPane
-	auxPane  *dirPane
 	prevPane *dirPane
 
-	panes          *tview.Flex
-	titleBar       *tview.Flex
-	mainFlex       *tview.Flex
-	wrapVertical   *tview.Flex
+	titleBar     *tview.Flex
+	mainFlex     *tview.Flex
+	wrapVertical *tview.Flex
 
-	boxVertical       *tview.Box
-	boxTitleSeparator *tview.Box
-	boxLogSeparator   *tview.Box
+	boxLogSeparator *tview.Box
 
 	appSuspend bool
 )
 
-func newDirPane(selpane bool) *dirPane {
-	var initPath string
-	var initMode ifaceMode
-
-	if selpane {
-		initMode = initSelMode
-		initPath = initSelPath
-	} else {
-		initMode = initAuxMode
-		initPath = initAuxPath
-	}
-
+func newDirPane(mode ifaceMode, path string) *dirPane {
 	return &dirPane{
-		mode:   initMode,
-		path:   initPath,
-		apath:  initAPath,
-		dpath:  initLPath,
-		table:  tview.NewTable(),
-		title:  tview.NewTextView(),
-		plock:  semaphore.NewWeighted(1),
-		hidden: true,
+		mode:       mode,
+		path:       path,
+		apath:      initAPath,
+		dpath:      initLPath,
+		table:      tview.NewTable(),
+		title:      tview.NewTextView(),
+		plock:      semaphore.NewWeighted(1),
+		hidden:     !initHidden,
+		sortMethod: initSort,
+		free:       -1,
 	}
 }
 
@@ -96,24 +89,19 @@ func setupUI() {
 	pages.SwitchToPage("main")
 
 	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyCtrlC:
+		if event.Key() == tcell.KeyCtrlC {
 			return nil
-
-		case tcell.KeyCtrlD:
-			execCmd("", "Foreground", "Local")
-
-		case tcell.KeyCtrlZ:
-			appSuspend = true
 		}
 
-		if event.Modifiers() != tcell.ModAlt {
-			return event
-		}
+		switch getKeyAction(kGlobal, event) {
+		case "local-shell":
+			execCmd("", "Foreground", "Local")
 
-		switch event.Rune() {
-		case 'd':
+		case "adb-shell":
 			execCmd("", "Foreground", "Adb")
+
+		case "suspend":
+			appSuspend = true
 		}
 
 		return event
@@ -135,37 +123,24 @@ func setupUI() {
 }
 
 func setupPaneView() *tview.Flex {
-	selPane, auxPane = newDirPane(true), newDirPane(false)
-
-	prevPane = selPane
-
 	setupStatus()
 
-	selPane.focused = true
-	auxPane.focused = false
+	tabBar = newTextView()
+	tabPages = tview.NewPages()
+	titlePages = tview.NewPages()
 
-	setupPane(selPane, auxPane, true)
-	setupPane(auxPane, selPane, true)
-
-	boxVertical = tview.NewBox().
-		SetBackgroundColor(tcell.ColorDefault).
-		SetDrawFunc(func(screen tcell.Screen, x, y, width, height int) (int, int, int, int) {
-			centerX := x + width/2
-			for cy := y; cy < y+height; cy++ {
-				screen.SetContent(
-					centerX,
-					cy,
-					tview.BoxDrawingsLightVertical,
-					nil,
-					tcell.StyleDefault.Foreground(tcell.ColorDefault),
-				)
-			}
+	titleBar = tview.NewFlex().
+		AddItem(tabBar, 0, 0, false).
+		AddItem(titlePages, 0, 1, false)
 
-			return x + 1, centerX + 1, width - 2, height - (centerX + 1 - y)
-		})
+	if lastSession != nil {
+		lastSession.restore()
+	}
 
-	boxTitleSeparator = tview.NewBox().
-		SetBackgroundColor(tcell.ColorDefault)
+	if len(tabs) == 0 {
+		newTab("", newDirPane(initSelMode, initSelPath), newDirPane(initAuxMode, initAuxPath))
+		switchTab(0)
+	}
 
 	boxLogSeparator = tview.NewBox().
 		SetBackgroundColor(tcell.ColorDefault).
@@ -183,24 +158,15 @@ func setupPaneView() *tview.Flex {
 			return x, y, width, height
 		})
 
-	panes = tview.NewFlex().
-		AddItem(selPane.table, 0, 1, true).
-		AddItem(boxVertical, 5, 0, false).
-		AddItem(auxPane.table, 0, 1, false).
-		SetDirection(tview.FlexColumn)
-
-	titleBar = tview.NewFlex().
-		AddItem(selPane.title, 0, 1, true).
-		AddItem(boxTitleSeparator, 1, 0, true).
-		AddItem(auxPane.title, 0, 1, false)
-
 	wrapPanes := tview.NewFlex().
-		AddItem(panes, 0, 2, true).
+		AddItem(tabPages, 0, 2, true).
 		SetDirection(tview.FlexRow)
 
 	wrapView := tview.NewFlex().
 		AddItem(wrapPanes, 0, 2, false)
 
+	setupPreview(wrapView)
+
 	wrapFlex := tview.NewFlex().
 		AddItem(wrapView, 0, 1, true)
 
@@ -250,24 +216,21 @@ func setupOpsView() *tview.Flex {
 	}
 
 	opsView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyEscape:
-			exit()
-		}
-
-		switch event.Rune() {
-		case 'x':
+		switch getKeyAction(kOps, event) {
+		case "ops-cancel":
 			canceltask()
 
-		case 'X':
+		case "ops-cancel-all":
 			cancelAllOps()
 
-		case 'o':
+		case "ops-exit":
 			exit()
 
-		case 'q':
-			pages.SwitchToPage("main")
-			stopApp()
+		default:
+			if getKeyAction(kMain, event) == "quit" {
+				pages.SwitchToPage("main")
+				stopApp()
+			}
 		}
 
 		return event
@@ -275,7 +238,7 @@ func setupOpsView() *tview.Flex {
 
 	opsView.SetSelectable(true, false)
 
-	opsTitle.SetText("[::bu]Operations")
+	opsTitle.SetText(getStyle("title").tag() + "Operations")
 
 	opsView.SetBorderColor(tcell.ColorDefault)
 	opsView.SetBackgroundColor(tcell.ColorDefault)
@@ -288,82 +251,189 @@ func setupPane(selPane, auxPane *dirPane, loadDir bool) {
 	selPane.table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		prevPane = selPane
 
-		switch event.Key() {
-		case tcell.KeyEscape:
+		switch getKeyAction(kMain, event) {
+		case "reset":
 			reset(selPane, auxPane)
 
-		case tcell.KeyTab, tcell.KeyBacktab:
+		case "switch-pane":
 			paneswitch(selPane, auxPane)
 
-		case tcell.KeyCtrlO:
-			go selPane.openFileHandler()
+		case "open":
+			selPane.updateRef(false)
+			go selPane.openFileHandler(selPane.entry, nil)
+
+		case "open-with":
+			selPane.showOpenWith()
+			return nil
+
+		case "opened-files":
+			selPane.showOpenedFiles()
+			return nil
+
+		case "install":
+			selPane.installHandler(auxPane)
+			return nil
+
+		case "apps":
+			selPane.showApps(auxPane)
+			return nil
+
+		case "run-as":
+			selPane.showRunAs()
+			return nil
+
+		case "root-mode":
+			selPane.toggleRoot()
+			return nil
 
-		case tcell.KeyCtrlR:
+		case "clear-filter":
 			selPane.reselect(true)
 
-		case tcell.KeyEnter, tcell.KeyRight:
+		case "cd-entry":
 			selPane.ChangeDirEvent(true, false)
 			return nil
 
-		case tcell.KeyBackspace, tcell.KeyBackspace2, tcell.KeyLeft:
+		case "cd-back":
 			selPane.ChangeDirEvent(false, true)
 			return nil
-		}
 
-		switch event.Rune() {
-		case 'o':
+		case "ops-page":
 			opsPage()
 
-		case 'q':
+		case "quit":
 			stopApp()
 
-		case '?':
+		case "help":
 			showHelp()
 
-		case 'h', '.':
+		case "toggle-hidden":
 			selPane.setHidden()
 
-		case '/':
+		case "filter":
 			selPane.showFilterInput()
 			return nil
 
-		case ';':
+		case "sort":
 			selPane.showSortDirInput()
 			return nil
 
-		case 's', '<':
+		case "switch-mode":
 			selPane.modeSwitchHandler()
 
-		case 'g', '>':
+		case "change-dir":
 			selPane.showChangeDirInput()
 			return nil
 
-		case 'r':
+		case "refresh":
 			selPane.ChangeDir(false, false)
 
-		case 'S':
+		case "edit-selections":
 			showEditSelections(nil)
 
-		case 'l':
+		case "log":
 			showFullscreenLog()
 
-		case '[':
+		case "history-back":
+			selPane.saveViewState()
 			go selPane.navigateHistory(false)
 
-		case ']':
+		case "history-forward":
+			selPane.saveViewState()
 			go selPane.navigateHistory(true)
 
-		case '!':
+		case "exec":
 			execCommand()
 
-		case 'A', 'a', ' ':
-			multiselect(selPane, event.Rune())
+		case "select-all":
+			multiselect(selPane, 'A')
+
+		case "select-invert":
+			multiselect(selPane, 'a')
 
-		case 'm', 'p', 'P', 'd':
-			opsHandler(selPane, auxPane, event.Rune())
+		case "select-one":
+			multiselect(selPane, ' ')
+
+		case "paste":
+			opsHandler(selPane, auxPane, 'p')
+
+		case "paste-overwrite":
+			opsHandler(selPane, auxPane, 'P')
+
+		case "move":
+			opsHandler(selPane, auxPane, 'm')
+
+		case "delete":
+			opsHandler(selPane, auxPane, 'd')
+
+		case "bookmark-add":
+			selPane.showAddBookmarkInput()
+			return nil
+
+		case "bookmarks":
+			selPane.showBookmarks()
+			return nil
 
-		case 'M', 'R':
-			showMkdirRenameInput(selPane, auxPane, event.Rune())
+		case "bookmark-jump":
+			selPane.jumpToBookmark(getKeyIndex("bookmark-jump", event))
+			return nil
+
+		case "preview":
+			togglePreview()
+			return nil
+
+		case "view":
+			selPane.editFileHandler(true)
+			return nil
+
+		case "edit":
+			selPane.editFileHandler(false)
+			return nil
+
+		case "info":
+			selPane.showFileInfo()
+			return nil
+
+		case "volume-switch":
+			selPane.showVolumeSwitcher()
+			return nil
+
+		case "volumes":
+			selPane.showVolumes()
+			return nil
+
+		case "disk-usage":
+			selPane.showDiskUsage()
+			return nil
+
+		case "fuzzy":
+			selPane.showFuzzyFinder()
+			return nil
+
+		case "search":
+			selPane.showSearchInput()
+			return nil
+
+		case "tab-new":
+			newTabFrom(tabs[tabPos])
+
+		case "tab-close":
+			closeTab()
+
+		case "tab-rename":
+			showRenameTabInput()
+			return nil
+
+		case "tab-next":
+			cycleTab(true)
+
+		case "tab-prev":
+			cycleTab(false)
+
+		case "mkdir":
+			showMkdirRenameInput(selPane, auxPane, 'M')
+
+		case "rename":
+			showMkdirRenameInput(selPane, auxPane, 'R')
 		}
 
 		return event
@@ -392,8 +462,11 @@ func setupPane(selPane, auxPane *dirPane, loadDir bool) {
 				continue
 			}
 
-			cell.SetSelectedStyle(tcell.Style{}.
-				Attributes(tcell.AttrReverse))
+			cell.SetSelectedStyle(getStyle("cursor").style())
+		}
+
+		if selPane.focused {
+			updatePreview(selPane, false)
 		}
 
 		cell := selPane.table.GetCell(row, 0)
@@ -402,7 +475,7 @@ func setupPane(selPane, auxPane *dirPane, loadDir bool) {
 			if ref != nil {
 				dir := ref.(*adb.DirEntry)
 				if dir.Name != ".." && len(dir.Name) > 50 {
-					sendMessage(message{"[::b]Highlighted: " + tview.Escape(dir.Name), true})
+					sendMessage(message{getStyle("info").tag() + "Highlighted: " + tview.Escape(dir.Name), true})
 				} else {
 					sendMessage(message{"", true})
 				}
@@ -446,6 +519,7 @@ func paneswitch(selPane, auxPane *dirPane) {
 
 	auxPane.reselect(false)
 	app.SetFocus(auxPane.table)
+	updatePreview(auxPane, false)
 	selPane.table.SetSelectable(false, false)
 	auxPane.table.SetSelectable(true, false)
 }
@@ -562,12 +636,9 @@ func (p *dirPane) reselect(force bool) {
 }
 
 func (p *dirPane) updateDirPane(row int, sel bool, dir *adb.DirEntry) {
-	entry := getListEntry(dir)
+	entry := getListEntry(dir, entrySize(p.sizes, dir))
 
-	perms := strings.ToLower(dir.Mode.String())
-	if len(perms) > 10 {
-		perms = perms[1:]
-	}
+	perms := getEntryPerms(dir)
 
 	// For ".." parent directory, only show the name column
 	isParentDir := dir.Name == ".."
@@ -585,11 +656,10 @@ func (p *dirPane) updateDirPane(row int, sel bool, dir *adb.DirEntry) {
 			}
 		}
 
-		color, attr := setEntryColor(col, sel, perms)
+		style := setEntryColor(col, sel, perms, dir)
 
 		cell := tview.NewTableCell(tview.Escape(dname))
 		cell.SetReference(dir)
-		cell.SetBackgroundColor(tcell.ColorDefault)
 
 		if col > 0 {
 			// Column 1: size (right-aligned)
@@ -608,8 +678,7 @@ func (p *dirPane) updateDirPane(row int, sel bool, dir *adb.DirEntry) {
 			cell.SetMaxWidth(maxWidth)
 		}
 
-		p.table.SetCell(row, col, cell.SetTextColor(color).
-			SetAttributes(attr))
+		p.table.SetCell(row, col, cell.SetStyle(style.style()))
 	}
 }
 
@@ -642,6 +711,9 @@ func (p *dirPane) setPaneTitle() {
 	switch p.mode {
 	case mAdb:
 		prefix = "Adb"
+		if a := getShellAccess(p.path, p.getSu()); a != nil {
+			prefix += " (" + a.String() + ")"
+		}
 
 	case mLocal:
 		prefix = "Local"
@@ -655,18 +727,26 @@ func (p *dirPane) setPaneTitle() {
 		p.path = trimPath(p.path, false)
 	}
 
+	var free string
+
+	if p.free >= 0 {
+		free = " (" + formatFileSize(p.free) + " free)"
+	}
+
 	dpath := tview.Escape(p.path)
 	_, _, titleWidth, _ := p.title.GetRect()
 
-	if len(dpath) > titleWidth {
+	if len(dpath) > titleWidth-len(free) {
 		dir := trimPath(dpath, true)
 		base := filepath.Base(dpath)
 
-		dir = trimName(dir, titleWidth-len(base)-20, true)
+		dir = trimName(dir, titleWidth-len(free)-len(base)-20, true)
 		dpath = dir + base
 	}
 
-	p.title.SetText("[::bu]" + prefix + ": " + dpath)
+	p.title.SetText(getStyle("title").tag() + prefix + ": " + dpath + free)
+
+	updateTabBar()
 }
 
 func (p *dirPane) setPaneSelectable(status bool) {
@@ -725,6 +805,20 @@ func stopApp() {
 }
 
 func stopUI() {
+	for _, pane := range allPanes() {
+		pane.saveViewState()
+	}
+
+	if err := saveViewStates(); err != nil {
+		addLog("viewstate", err.Error(), true)
+	}
+
+	if err := saveSession(); err != nil {
+		addLog("session", err.Error(), true)
+	}
+
+	stopOpenedFiles()
+
 	app.Stop()
 	stopStatus()
 	cancelAllOps()
@@ -736,82 +830,20 @@ func showHelp() {
 	helpview := tview.NewTable()
 	helpview.SetBackgroundColor(tcell.ColorDefault)
 
-	helpview.SetSelectedStyle(tcell.Style{}.
-		Attributes(tcell.AttrReverse))
+	helpview.SetSelectedStyle(getStyle("cursor").style())
 
 	helpview.SetBorderColor(tcell.ColorDefault)
 
-	mainText := map[string]string{
-		"Switch between panes ":                 "Tab ",
-		"Navigate between entries ":             "Up, Down",
-		"CD highlighted entry ":                 "Enter, Right",
-		"Change one directory back ":            "Backspace, Left",
-		"Switch to operations page ":            "o",
-		"View fullscreen log ":                  "l",
-		"Switch between ADB/Local ":             "s, <",
-		"Change to any directory ":              "g, >",
-		"Toggle hidden files ":                  "h, .",
-		"Execute command":                       "!",
-		"Refresh ":                              "r",
-		"Move ":                                 "m",
-		"Paste/Put ":                            "p",
-		"Delete ":                               "d",
-		"Open files ":                           "Ctrl+o",
-		"Make directory ":                       "M",
-		"Rename files/folders ":                 "R",
-		"Filter entries":                        "/",
-		"Toggle filtering modes (normal/regex)": "/",
-		"Sort entries":                          ";",
-		"Clear filtered entries ":               "Ctrl+r",
-		"Select one item ":                      "Space",
-		"Invert selection ":                     "a",
-		"Select all items ":                     "A",
-		"Edit selection list ":                  "S",
-		"Navigate back in history ":             "[",
-		"Navigate forward in history ":          "]",
-		"Reset selections ":                     "Esc",
-		"Temporarily exit to shell ":            "Ctrl+d",
-		"Quit ":                                 "q",
-	}
-
-	opnsText := map[string]string{
-		"Navigate between entries ":  "Up, Down",
-		"Cancel selected operation ": "x",
-		"Cancel all operations ":     "X",
-		"Switch to main page ":       "o, Esc",
-	}
-
-	cdirText := map[string]string{
-		"Navigate between entries ": "Up, Down",
-		"Autocomplete ":             "Tab",
-		"CD to highlighted entry ":  "Enter",
-		"Move back a directory ":    "Ctrl+w",
-		"Switch to main page ":      "Esc",
-	}
-
-	editText := map[string]string{
-		"Select one item ":     "Alt+Space",
-		"Invert selection ":    "Alt+a",
-		"Select all items ":    "Alt+A",
-		"Save edited list ":    "Ctrl+s",
-		"Cancel editing list ": "Esc",
-	}
-
-	execText := map[string]string{
-		"Switch b/w Local/Adb ":       "Ctrl+a",
-		"Switch b/w FG/BG execution ": "Ctrl+q",
-	}
-
 	helpview.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		switch event.Key() {
 		case tcell.KeyEscape, tcell.KeyEnter:
 			pages.SwitchToPage("main")
 			app.SetFocus(prevPane.table)
 			prevPane.table.SetSelectable(true, false)
+			return event
 		}
 
-		switch event.Rune() {
-		case 'q':
+		if getKeyAction(kMain, event) == "quit" {
 			pages.SwitchToPage("main")
 			stopApp()
 		}
@@ -827,33 +859,27 @@ func showHelp() {
 		}
 	})
 
-	for i, helpMap := range []map[string]string{
-		mainText,
-		opnsText,
-		cdirText,
-		editText,
-		execText,
+	for _, ctx := range []keyContext{
+		kMain,
+		kGlobal,
+		kOps,
+		kChangeDir,
+		kEditSel,
+		kFilter,
+		kExec,
+		kBookmarks,
+		kSearch,
+		kFuzzy,
+		kDiskUsage,
+		kVolumes,
+		kPager,
+		kEditor,
+		kOpenWith,
+		kOpened,
+		kApps,
+		kLog,
 	} {
-		var header string
-
-		switch i {
-		case 0:
-			header = "MAIN PAGE"
-
-		case 1:
-			header = "OPERATIONS PAGE"
-
-		case 2:
-			header = "CHANGE DIRECTORY MODE"
-
-		case 3:
-			header = "EDIT SELECTION MODE"
-
-		case 4:
-			header = "EXECUTION MODE"
-		}
-
-		helpview.SetCell(row, 0, tview.NewTableCell("[::b]["+header+"[]").
+		helpview.SetCell(row, 0, tview.NewTableCell("[::b]["+ctx.String()+"[]").
 			SetExpansion(1).
 			SetSelectable(false).
 			SetTextColor(tcell.ColorDefault).
@@ -877,10 +903,14 @@ func showHelp() {
 			SetSelectable(false))
 		row++
 
-		for k, v := range helpMap {
-			helpview.SetCell(row, 0, tview.NewTableCell(k).
+		for _, action := range keyActions {
+			if action.ctx != ctx {
+				continue
+			}
+
+			helpview.SetCell(row, 0, tview.NewTableCell(action.desc+" ").
 				SetTextColor(tcell.ColorDefault))
-			helpview.SetCell(row, 1, tview.NewTableCell(v).
+			helpview.SetCell(row, 1, tview.NewTableCell(tview.Escape(getKeyNames(action.name))).
 				SetTextColor(tcell.ColorDefault))
 
 			row++
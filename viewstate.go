@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	adb "github.com/zach-klippenstein/goadb"
+)
+
+type sortState struct {
+	SortBy     string `json:"sort_by"`
+	ArrangeBy  string `json:"arrange_by"`
+	Natural    bool   `json:"natural,omitempty"`
+	IgnoreCase bool   `json:"ignore_case,omitempty"`
+	Mixed      bool   `json:"mixed,omitempty"`
+}
+
+type viewState struct {
+	sortState
+	Filter string    `json:"filter,omitempty"`
+	Regex  bool      `json:"regex,omitempty"`
+	Cursor string    `json:"cursor,omitempty"`
+	Used   time.Time `json:"used"`
+}
+
+const maxViewStates = 1000
+
+var (
+	viewStates    map[string]viewState
+	viewStateLock sync.Mutex
+)
+
+func viewStateKey(mode ifaceMode, path string) string {
+	path = filepath.Clean(path)
+
+	switch mode {
+	case mAdb:
+		return "adb:" + deviceSerial + ":" + path
+	}
+
+	return "local:" + path
+}
+
+func loadViewStates() {
+	viewStateLock.Lock()
+	defer viewStateLock.Unlock()
+
+	viewStates = make(map[string]viewState)
+
+	vpath := configPath("viewstate.json")
+	if vpath == "" {
+		return
+	}
+
+	data, err := os.ReadFile(vpath)
+	if err != nil {
+		return
+	}
+
+	json.Unmarshal(data, &viewStates)
+}
+
+func saveViewStates() error {
+	viewStateLock.Lock()
+	defer viewStateLock.Unlock()
+
+	vpath := configPath("viewstate.json")
+	if vpath == "" || viewStates == nil {
+		return nil
+	}
+
+	if len(viewStates) > maxViewStates {
+		var keys []string
+
+		for key := range viewStates {
+			keys = append(keys, key)
+		}
+
+		sort.Slice(keys, func(i, j int) bool {
+			return viewStates[keys[i]].Used.After(viewStates[keys[j]].Used)
+		})
+
+		for _, key := range keys[maxViewStates:] {
+			delete(viewStates, key)
+		}
+	}
+
+	data, err := json.Marshal(viewStates)
+	if err != nil {
+		return err
+	}
+
+	return writeConfigFile(vpath, data)
+}
+
+// saveViewState records the sort method, filter and highlighted entry
+// of the directory currently shown in the pane.
+func (p *dirPane) saveViewState() {
+	if p.vkey == "" {
+		return
+	}
+
+	var cursor string
+
+	row, _ := p.table.GetSelection()
+	if cell := p.table.GetCell(row, 0); cell != nil {
+		if ref := cell.GetReference(); ref != nil {
+			cursor = ref.(*adb.DirEntry).Name
+		}
+	}
+
+	state := viewState{
+		sortState: p.getSortMethod().state(),
+		Cursor:    cursor,
+		Used:      time.Now(),
+	}
+
+	if p.filter {
+		state.Filter = p.finput
+		state.Regex = p.fregex
+	}
+
+	viewStateLock.Lock()
+	defer viewStateLock.Unlock()
+
+	if viewStates != nil {
+		viewStates[p.vkey] = state
+	}
+}
+
+// loadViewState restores the view state for the directory identified by
+// key, if one was saved. The pane keeps its current sort method otherwise.
+func (p *dirPane) loadViewState(key string) {
+	viewStateLock.Lock()
+	state, ok := viewStates[key]
+	viewStateLock.Unlock()
+
+	p.vkey = key
+	p.vrestore = true
+
+	p.finput = ""
+	p.fregex = false
+	p.filter = false
+	p.vcursor = ""
+
+	if !ok {
+		return
+	}
+
+	sortLock.Lock()
+	p.sortMethod = state.sortData()
+	sortLock.Unlock()
+
+	p.finput = state.Filter
+	p.fregex = state.Regex
+	p.filter = state.Filter != ""
+	p.vcursor = state.Cursor
+}
+
+func (s sortData) state() sortState {
+	return sortState{
+		SortBy:     s.sortBy,
+		ArrangeBy:  s.arrangeBy,
+		Natural:    s.natural,
+		IgnoreCase: s.ignoreCase,
+		Mixed:      s.mixed,
+	}
+}
+
+func (s sortState) sortData() sortData {
+	return sortData{
+		sortBy:     s.SortBy,
+		arrangeBy:  s.ArrangeBy,
+		natural:    s.Natural,
+		ignoreCase: s.IgnoreCase,
+		mixed:      s.Mixed,
+	}
+}
+
+// getViewList returns the entries to display, applying the pane's filter.
+func (p *dirPane) getViewList() []*adb.DirEntry {
+	if !p.filter || p.finput == "" {
+		return p.pathList
+	}
+
+	match, err := getFilterMatcher(p.finput, p.fregex)
+	if err != nil {
+		return p.pathList
+	}
+
+	var list []*adb.DirEntry
+
+	for _, dir := range p.pathList {
+		if match(dir.Name) {
+			list = append(list, dir)
+		}
+	}
+
+	return list
+}
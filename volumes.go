@@ -0,0 +1,708 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/darkhz/tview"
+	"github.com/gdamore/tcell/v2"
+	adb "github.com/zach-klippenstein/goadb"
+)
+
+type volume struct {
+	mode  ifaceMode
+	label string
+	name  string
+	fs    string
+	mount string
+	path  string
+	total int64
+	used  int64
+	free  int64
+}
+
+// parseDf parses the output of "df -k". Entries whose filesystem
+// name is too long may be wrapped onto a second line.
+func parseDf(out string) []volume {
+	var vols []volume
+	var wrapped string
+
+	for i, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(strings.TrimRight(line, "\r"))
+		if i == 0 || len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) == 1 {
+			wrapped = fields[0]
+			continue
+		}
+
+		if wrapped != "" {
+			fields = append([]string{wrapped}, fields...)
+			wrapped = ""
+		}
+
+		if len(fields) < 6 {
+			continue
+		}
+
+		var size [3]int64
+		var err error
+
+		for n := range size {
+			size[n], err = strconv.ParseInt(fields[n+1], 10, 64)
+			if err != nil {
+				break
+			}
+
+			size[n] *= 1024
+		}
+
+		if err != nil {
+			continue
+		}
+
+		mount := strings.Join(fields[5:], " ")
+
+		vols = append(vols, volume{
+			fs:    fields[0],
+			mount: mount,
+			path:  mount,
+			total: size[0],
+			used:  size[1],
+			free:  size[2],
+		})
+	}
+
+	return vols
+}
+
+// getAdbVolumes lists the data partition and the storage volumes of the
+// device. Public volumes are labelled by the kind of disk they are on.
+func getAdbVolumes() ([]volume, error) {
+	var vols []volume
+
+	device, err := getAdb()
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := runAdbShellCommand(device, "df -k")
+	if err != nil {
+		return nil, err
+	}
+
+	kinds := make(map[string]string)
+
+	smout, _ := runAdbShellCommand(device, "sm list-volumes all")
+	for _, line := range strings.Split(smout, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !strings.HasPrefix(fields[0], "public:") {
+			continue
+		}
+
+		major, _, _ := strings.Cut(strings.TrimPrefix(fields[0], "public:"), ",")
+
+		switch major {
+		case "179":
+			kinds[fields[2]] = "SD card"
+
+		case "8":
+			kinds[fields[2]] = "USB storage"
+		}
+	}
+
+	names := getAdbVolumeNames(device)
+
+	seen := make(map[string]bool)
+
+	for _, vol := range parseDf(out) {
+		switch {
+		case vol.mount == "/data":
+			vol.label = "Data"
+
+		case vol.mount == "/storage/emulated":
+			vol.label = "Internal storage"
+			vol.path = "/storage/emulated/0"
+
+		case strings.HasPrefix(vol.mount, "/storage/") && vol.mount != "/storage/self":
+			uuid := filepath.Base(vol.mount)
+
+			vol.label = kinds[uuid]
+			if vol.label == "" {
+				vol.label = "External storage"
+			}
+
+			vol.name = names[uuid]
+
+		default:
+			continue
+		}
+
+		if seen[vol.mount] {
+			continue
+		}
+
+		seen[vol.mount] = true
+
+		vol.mode = mAdb
+		vols = append(vols, vol)
+	}
+
+	return vols, nil
+}
+
+// getAdbVolumeNames returns the filesystem labels of the public
+// volumes of the device, by filesystem UUID.
+func getAdbVolumeNames(device *adb.Device) map[string]string {
+	var uuid string
+
+	names := make(map[string]string)
+
+	logIndex := startLog("shell dumpsys mount")
+	out, err := device.RunCommand("dumpsys mount")
+	updateLog(logIndex, "", err != nil)
+
+	if err != nil {
+		return names
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+
+		if strings.HasPrefix(line, "VolumeInfo{") {
+			uuid = ""
+			continue
+		}
+
+		for _, field := range strings.Fields(line) {
+			if strings.HasPrefix(field, "fsUuid=") {
+				uuid = strings.TrimPrefix(field, "fsUuid=")
+			}
+		}
+
+		// The label may contain spaces, and is the last field of its line.
+		if _, label, ok := strings.Cut(line, "fsLabel="); ok && uuid != "" && uuid != "null" {
+			if label = strings.TrimSpace(label); label != "" && label != "null" {
+				names[uuid] = label
+			}
+		}
+	}
+
+	return names
+}
+
+// getLocalVolumes lists the mounted local filesystems backed by a device.
+func getLocalVolumes() ([]volume, error) {
+	var vols []volume
+
+	out, err := exec.Command("df", "-kP").Output()
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+
+	for _, vol := range parseDf(string(out)) {
+		if !strings.HasPrefix(vol.fs, "/dev/") ||
+			strings.HasPrefix(vol.fs, "/dev/loop") ||
+			strings.HasPrefix(vol.mount, "/snap/") ||
+			strings.HasPrefix(vol.mount, "/System/Volumes/") {
+			continue
+		}
+
+		if seen[vol.mount] {
+			continue
+		}
+
+		seen[vol.mount] = true
+
+		vol.mode = mLocal
+		vol.label = filepath.Base(vol.mount)
+		if vol.mount == "/" {
+			vol.label = "Root"
+		}
+
+		vols = append(vols, vol)
+	}
+
+	return vols, nil
+}
+
+func getVolumes(mode ifaceMode) ([]volume, error) {
+	switch mode {
+	case mAdb:
+		vols, err := getAdbVolumes()
+		if err != nil {
+			return nil, err
+		}
+
+		return append(vols, volume{mode: mAdb, label: "Apps", name: "installed apps", path: appsLocation}), nil
+	}
+
+	return getLocalVolumes()
+}
+
+func (v volume) String() string {
+	if v.name == "" || v.name == v.label {
+		return v.label
+	}
+
+	return v.label + " (" + v.name + ")"
+}
+
+// getFreeSpace returns the space available on the filesystem containing path.
+func getFreeSpace(mode ifaceMode, path string) (int64, error) {
+	switch mode {
+	case mAdb:
+		device, err := getAdb()
+		if err != nil {
+			return -1, err
+		}
+
+		// Note: df calls are deliberately not logged, since
+		// they occur on every directory change.
+		out, err := device.RunCommand("df -k " + shellQuote(path))
+		if err != nil {
+			return -1, err
+		}
+
+		vols := parseDf(out)
+		if len(vols) == 0 {
+			return -1, fmt.Errorf("Cannot get free space of %s", path)
+		}
+
+		return vols[len(vols)-1].free, nil
+	}
+
+	var st syscall.Statfs_t
+
+	if err := syscall.Statfs(path, &st); err != nil {
+		return -1, err
+	}
+
+	return int64(st.Bavail) * int64(st.Bsize), nil
+}
+
+// updateFreeSpace shows the free space of the pane's directory in its
+// title, once it is read in the background.
+func (p *dirPane) updateFreeSpace() {
+	mode, path := p.mode, p.path
+
+	p.free = -1
+
+	go func() {
+		free, err := getFreeSpace(mode, path)
+		if err != nil {
+			return
+		}
+
+		go app.QueueUpdateDraw(func() {
+			if p.mode != mode || filepath.Clean(p.getPath()) != filepath.Clean(path) {
+				return
+			}
+
+			p.free = free
+			p.setPaneTitle()
+		})
+	}()
+}
+
+// getMountPoint returns the mount point of the filesystem holding path.
+func getMountPoint(mode ifaceMode, path string, su bool) (string, error) {
+	if mode == mAdb {
+		device, err := getAdb()
+		if err != nil {
+			return "", err
+		}
+
+		out, err := runAdbShellCommand(device, wrapAdbCommand(path, "df -k "+shellQuote(path), su))
+		if err != nil {
+			return "", err
+		}
+
+		vols := parseDf(out)
+		if len(vols) == 0 {
+			return "", fmt.Errorf("Cannot get the filesystem of %s", path)
+		}
+
+		return vols[len(vols)-1].mount, nil
+	}
+
+	var st syscall.Stat_t
+
+	if err := syscall.Stat(path, &st); err != nil {
+		return "", err
+	}
+
+	return strconv.FormatUint(uint64(st.Dev), 10), nil
+}
+
+// getSourceSize returns the size of the files at path.
+func getSourceSize(mode ifaceMode, path string, su bool) (int64, error) {
+	var size int64
+
+	if mode == mAdb {
+		device, err := getAdb()
+		if err != nil {
+			return 0, err
+		}
+
+		out, err := runAdbShellCommand(device, wrapAdbCommand(path, "du -sk "+shellQuote(path), su))
+		if err != nil {
+			return 0, err
+		}
+
+		fields := strings.Fields(out)
+		if len(fields) == 0 {
+			return 0, fmt.Errorf("Cannot get the size of %s", path)
+		}
+
+		size, err = strconv.ParseInt(fields[0], 10, 64)
+
+		return size * 1024, err
+	}
+
+	err := filepath.Walk(path, func(p string, entry os.FileInfo, err error) error {
+		if err == nil && !entry.IsDir() {
+			size += entry.Size()
+		}
+
+		return nil
+	})
+
+	return size, err
+}
+
+// writesData reports whether the operation writes the data of src to
+// dst, rather than only renaming it within the same filesystem.
+// A move is assumed to write the data if the filesystems cannot be
+// determined, so that the free space is still checked.
+func (o *operation) writesData(src, dst string, smode, dmode ifaceMode) bool {
+	switch o.opmode {
+	case opCopy:
+		return true
+
+	case opMove:
+		if smode != dmode {
+			return true
+		}
+
+		smount, err := getMountPoint(smode, src, o.su)
+		if err != nil {
+			return true
+		}
+
+		dmount, err := getMountPoint(dmode, filepath.Dir(dst), o.su)
+
+		return err != nil || smount != dmount
+	}
+
+	return false
+}
+
+// checkFreeSpace refuses to copy or move when the size of the source
+// exceeds the space available at the destination.
+func (o *operation) checkFreeSpace(src, dst string, smode, dmode ifaceMode) error {
+	if !o.writesData(src, dst, smode, dmode) {
+		return nil
+	}
+
+	size := o.totalBytes
+	if size <= 0 {
+		var err error
+
+		size, err = getSourceSize(smode, src, o.su)
+		if err != nil {
+			addLog("checkFreeSpace", fmt.Sprintf("cannot get source size: %v", err), true)
+			return nil
+		}
+	}
+
+	if size <= 0 {
+		return nil
+	}
+
+	free, err := getFreeSpace(dmode, filepath.Dir(dst))
+	if err != nil {
+		addLog("checkFreeSpace", fmt.Sprintf("cannot check free space: %v", err), true)
+		return nil
+	}
+
+	if size > free {
+		return fmt.Errorf(
+			"Not enough space for '%s': %s needed, %s available",
+			filepath.Base(dst), formatFileSize(size), formatFileSize(free),
+		)
+	}
+
+	return nil
+}
+
+func (p *dirPane) showVolumes() {
+	voltable := tview.NewTable()
+	voltitle := newTextView()
+
+	flex := tview.NewFlex().
+		AddItem(voltitle, 1, 0, false).
+		AddItem(voltable, 0, 1, true).
+		SetDirection(tview.FlexRow)
+
+	exit := func() {
+		pages.SwitchToPage("main")
+		pages.RemovePage("volumes")
+
+		app.SetFocus(p.table)
+	}
+
+	load := func() {
+		var vols []volume
+		var errs []string
+
+		if _, err := getAdb(); err == nil {
+			avols, err := getAdbVolumes()
+			if err != nil {
+				errs = append(errs, err.Error())
+			}
+
+			vols = append(vols, avols...)
+		}
+
+		lvols, err := getLocalVolumes()
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+
+		vols = append(vols, lvols...)
+
+		go app.QueueUpdateDraw(func() {
+			title := getStyle("title").tag() + "Volumes"
+			if errs != nil {
+				title += " (" + tview.Escape(strings.Join(errs, ", ")) + ")"
+			}
+
+			voltitle.SetText(title)
+
+			voltable.Clear()
+
+			for row, vol := range vols {
+				var percent float64
+
+				if vol.total > 0 {
+					percent = float64(vol.used) * 100 / float64(vol.total)
+				}
+
+				fill := int(percent * duBarWidth / 100)
+				bar := strings.Repeat("█", fill) + strings.Repeat("░", duBarWidth-fill)
+
+				cells := []*tview.TableCell{
+					tview.NewTableCell(vol.mode.String() + " "),
+					tview.NewTableCell(tview.Escape(vol.String()) + " "),
+					tview.NewTableCell(tview.Escape(vol.mount) + " ").SetExpansion(1),
+					tview.NewTableCell(bar + " ").SetStyle(getStyle("progress").style()),
+					tview.NewTableCell(fmt.Sprintf("%5.1f%% ", percent)).SetAlign(tview.AlignRight),
+					tview.NewTableCell(formatFileSize(vol.free) + " free of " + formatFileSize(vol.total)).
+						SetAlign(tview.AlignRight),
+				}
+
+				for col, cell := range cells {
+					if col != 3 {
+						cell.SetStyle(getStyle("column").style())
+					}
+
+					voltable.SetCell(row, col, cell.SetReference(vol))
+				}
+
+				voltable.GetCell(row, 1).SetStyle(getStyle("directory").style())
+			}
+
+			voltable.Select(0, 0)
+		})
+	}
+
+	voltable.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch getKeyAction(kVolumes, event) {
+		case "volume-select":
+			row, _ := voltable.GetSelection()
+
+			vol, ok := voltable.GetCell(row, 0).GetReference().(volume)
+			exit()
+
+			if ok {
+				showInfoMsg("Changing directory to " + vol.path)
+				p.jumpToEntry(vol.mode, vol.path, "")
+			}
+
+			return nil
+
+		case "volume-refresh":
+			voltitle.SetText(getStyle("title").tag() + "Volumes (loading)")
+			go load()
+
+			return nil
+
+		case "volume-exit":
+			exit()
+			return nil
+		}
+
+		return event
+	})
+
+	voltitle.SetText(getStyle("title").tag() + "Volumes (loading)")
+
+	voltable.SetSelectable(true, false)
+	voltable.SetBackgroundColor(tcell.ColorDefault)
+	voltable.SetSelectedStyle(getStyle("cursor").style())
+
+	pages.AddAndSwitchToPage("volumes", flex, true)
+	app.SetFocus(voltable)
+
+	go load()
+}
+
+// showVolumeSwitcher lists the volumes of the pane's mode
+// in a popup, to change to one of them in one step.
+func (p *dirPane) showVolumeSwitcher() {
+	if p.mode == mAdb && !checkAdb() {
+		return
+	}
+
+	var vols []volume
+
+	input := getStatusInput("Volumes (loading):", false)
+
+	voltable := tview.NewTable()
+
+	flex := tview.NewFlex().
+		AddItem(voltable, 0, 10, false).
+		SetDirection(tview.FlexRow)
+
+	closed := false
+
+	exit := func() {
+		closed = true
+
+		popupStatus(false)
+		pages.SwitchToPage("main")
+		statuspgs.SwitchToPage("statusmsg")
+		app.SetFocus(p.table)
+	}
+
+	reload := func(text string) {
+		var row int
+
+		voltable.Clear()
+
+		for _, vol := range vols {
+			label := vol.path + " " + vol.String()
+			if !strings.Contains(strings.ToLower(label), strings.ToLower(text)) {
+				continue
+			}
+
+			info := vol.String()
+			if vol.total > 0 {
+				info += ", " + formatFileSize(vol.free) + " free"
+			}
+
+			cell := tview.NewTableCell(fmt.Sprintf(
+				"[::b]%s[::-] (%s)",
+				tview.Escape(vol.path), tview.Escape(info),
+			))
+			cell.SetReference(vol)
+			voltable.SetCell(row, 0, cell.SetTextColor(tcell.ColorDefault))
+
+			row++
+		}
+
+		if row == 0 {
+			pages.HidePage("volumemodal")
+		} else {
+			if pg, _ := pages.GetFrontPage(); pg != "volumemodal" {
+				pages.SwitchToPage("volumemodal").ShowPage("main")
+			}
+
+			resizemodal()
+		}
+
+		app.SetFocus(input)
+
+		voltable.Select(0, 0)
+		voltable.ScrollToBeginning()
+	}
+
+	input.SetChangedFunc(func(text string) {
+		reload(text)
+	})
+
+	input.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch getKeyAction(kVolumes, event) {
+		case "volume-select":
+			row, _ := voltable.GetSelection()
+
+			vol, ok := voltable.GetCell(row, 0).GetReference().(volume)
+			exit()
+
+			if ok {
+				showInfoMsg("Changing directory to " + vol.path)
+				p.jumpToEntry(vol.mode, vol.path, "")
+			}
+
+			return nil
+
+		case "volume-exit":
+			exit()
+			return nil
+		}
+
+		switch event.Key() {
+		case tcell.KeyDown, tcell.KeyUp, tcell.KeyPgDn, tcell.KeyPgUp:
+			voltable.InputHandler()(event, nil)
+			return nil
+		}
+
+		return event
+	})
+
+	voltable.SetSelectedStyle(tcell.Style{}.
+		Bold(true).
+		Underline(true).
+		Reverse(true))
+
+	voltable.SetSelectable(true, false)
+	voltable.SetBackgroundColor(tcell.ColorDefault)
+
+	pages.AddAndSwitchToPage("volumemodal", statusmodal(flex, voltable), true).ShowPage("main")
+	pages.HidePage("volumemodal")
+
+	statuspgs.AddAndSwitchToPage("volumeswitcher", input, true)
+	app.SetFocus(input)
+
+	go func() {
+		v, err := getVolumes(p.mode)
+
+		go app.QueueUpdateDraw(func() {
+			if closed {
+				return
+			}
+
+			if err != nil {
+				exit()
+				showErrorMsg(err, false)
+
+				return
+			}
+
+			vols = v
+
+			input.SetLabel("[::b]Volumes: ")
+			reload(input.GetText())
+		})
+	}()
+}